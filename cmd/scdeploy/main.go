@@ -2,48 +2,177 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
-	"math/big"
 	"os"
-	"reflect"
 	"strings"
 
-	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/heliorosa/scui/internal"
-	"github.com/heliorosa/scui/signer"
 )
 
 func showHelpAndExit(msg string) {
 	if msg != "" {
 		fmt.Fprintf(os.Stderr, "\n%s\n", msg)
 	}
-	fmt.Fprintf(os.Stderr, "usage: %s <client_url> <bytecode_file> <abi_file> <signer_flag(s)> [gas_flag(s)] [--] [constructor_arguments]\n\n", os.Args[0])
-	signerArgs.newFlagSet("args", flag.ExitOnError).Usage()
+	fmt.Fprintf(os.Stderr, "usage: %s <client_url> <artifact_file | bytecode_file abi_file> <signer_flag(s)> [gas_flag(s)] [--] [constructor_arguments]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s <client_url> <artifact_file | bytecode_file abi_file> -args-file <args_file> <signer_flag(s)> [gas_flag(s)]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s <client_url> -plan <plan_file> <signer_flag(s)> [gas_flag(s)]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "artifacts can be hardhat, truffle or foundry artifacts or solc --combined-json\noutput (file.json:Name picks a contract)\n\n")
+	fmt.Fprintf(os.Stderr, "arrays and tuples in the constructor arguments are JSON, tuples as an array or\nan object by component name\n\n")
+	fmt.Fprintf(os.Stderr, "with -unsigned-out or -sign-only the transaction isn't sent, sign it and send it\nwith the sign and broadcast commands of scui\n\n")
+	fmt.Fprintf(os.Stderr, "plans are YAML or JSON files listing the contracts to deploy in order:\n%s\n", planExample)
+	newFlagSet().Usage()
 	os.Exit(-1)
 }
 
 // arguments defaults
-var signerArgs = &signatureArgsParser{
-	derivationPath: "m/44'/60'/x'/0/0",
-	gasPrice:       "0",
-	value:          "0",
+var (
+	signerArgs   = internal.NewSignatureArgsParser()
+	receiptArgs  = internal.NewReceiptArgs()
+	outputFormat = "table"
+	libraries    internal.LibrariesFlag
+	predict      bool
+	nonce        int64 = -1
+	create2      bool
+	salt         string
+	factory      = internal.DefaultCreate2Factory
+	planFile     string
+	planOut      = "deployments.json"
+	argsFile     string
+	verify       bool
+	unsignedOut  string
+	signOnly     bool
+)
+
+func newFlagSet() *flag.FlagSet {
+	fs := signerArgs.NewFlagSet("args", flag.ExitOnError)
+	receiptArgs.AddFlags(fs)
+	fs.BoolVar(&predict, "predict", predict, "print the address of the contract without deploying it")
+	fs.Int64Var(&nonce, "nonce", nonce, "nonce of the deployment for -predict (-1 for the next nonce of the signer)")
+	fs.BoolVar(&create2, "create2", create2, "deploy with CREATE2 through a factory, at an address that doesn't depend on the nonce")
+	fs.StringVar(&salt, "salt", salt, "hex CREATE2 salt, up to 32 bytes")
+	fs.StringVar(&factory, "factory", factory, "CREATE2 factory address, takes the salt followed by the init code")
+	fs.StringVar(&unsignedOut, "unsigned-out", unsignedOut, "write the unsigned transaction to a file instead of sending it, as JSON if the file is .json or as hex RLP. Takes -from instead of a signer")
+	fs.BoolVar(&signOnly, "sign-only", signOnly, "print the signed transaction instead of sending it")
+	fs.BoolVar(&verify, "verify", verify, "compare the deployed code with the deployed bytecode of the artifact, implies -wait")
+	fs.StringVar(&argsFile, "args-file", argsFile, "JSON file with the constructor arguments, an array in order or an object by name")
+	fs.StringVar(&planFile, "plan", planFile, "deploy the contracts of a deployment plan")
+	fs.StringVar(&planOut, "plan-out", planOut, "file where the addresses deployed by -plan are written, a plan that failed halfway resumes from it")
+	fs.Var(&libraries, "link", "library address as name=address, name can be qualified as file.sol:name (repeatable)")
+	fs.StringVar(&outputFormat, "output", outputFormat, internal.OutputFormatUsage)
+	fs.BoolVar(&internal.HumanUnits, "units", internal.HumanUnits, internal.HumanUnitsUsage)
+	return fs
+}
+
+// predictAddress returns the address where initCode would be deployed, by
+// the signer with CREATE or by the factory with CREATE2
+func predictAddress(cl *ethclient.Client, sigArgs *internal.SignatureArgs, initCode []byte) (*internal.PredictionRecord, error) {
+	r := &internal.PredictionRecord{}
+	if create2 {
+		factoryAddr, err := internal.ResolveAddress(factory)
+		if err != nil {
+			return nil, internal.WrapError("invalid factory address", err)
+		}
+		saltHash, err := internal.ParseSalt(salt)
+		if err != nil {
+			return nil, err
+		}
+		initCodeHash := crypto.Keccak256Hash(initCode)
+		r.Deployer, r.Salt, r.InitCodeHash = factoryAddr, &saltHash, &initCodeHash
+		r.Address = internal.Create2Address(factoryAddr, saltHash, initCode)
+	} else {
+		from := sigArgs.From()
+		n := uint64(nonce)
+		if nonce < 0 {
+			pn, err := cl.PendingNonceAt(context.Background(), from)
+			if err != nil {
+				return nil, internal.WrapError("can't get nonce", err)
+			}
+			n = pn
+		}
+		r.Deployer, r.Nonce = from, &n
+		r.Address = crypto.CreateAddress(from, n)
+	}
+	code, err := cl.CodeAt(context.Background(), r.Address, nil)
+	if err != nil {
+		return nil, internal.WrapError("can't get code", err)
+	}
+	r.Deployed = len(code) > 0
+	return r, nil
+}
+
+// readArgsFile reads the JSON constructor arguments in fn, keeping the
+// numbers as text to not lose precision
+func readArgsFile(fn string) (interface{}, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, internal.WrapError("can't read arguments file", err)
+	}
+	defer f.Close()
+	d := json.NewDecoder(f)
+	d.UseNumber()
+	var r interface{}
+	if err = d.Decode(&r); err != nil {
+		return nil, internal.WrapError("can't parse arguments file", err)
+	}
+	return r, nil
+}
+
+// verifyDeployment compares the code at addr with the deployed bytecode of
+// artifact, failing if they don't match. Code that can't be verified is only
+// reported
+func verifyDeployment(cl *ethclient.Client, output *internal.Printer, artifact *internal.Artifact, addr common.Address) error {
+	code, err := cl.CodeAt(context.Background(), addr, nil)
+	if err != nil {
+		return internal.WrapError("can't get code", err)
+	}
+	vr, err := artifact.VerifyCode(code)
+	if err != nil {
+		return err
+	}
+	vr.Address = addr
+	if err = output.Print(vr); err != nil {
+		return err
+	}
+	if !vr.Match && !vr.Unverifiable {
+		return errors.New("deployed code doesn't match the artifact")
+	}
+	return nil
+}
+
+// estimateError returns the error of estimating the gas of msg, which has the
+// revert reason, if bind failed to estimate it. bind drops the revert data
+func estimateError(cl *ethclient.Client, contractABI *abi.ABI, opts *bind.TransactOpts, msg ethereum.CallMsg, err error) error {
+	if opts.GasLimit != 0 {
+		return err
+	}
+	if _, eerr := internal.EstimateGas(cl, contractABI, msg); eerr != nil {
+		return eerr
+	}
+	return err
 }
 
 func main() {
 	// split arguments
-	if len(os.Args) < 4 {
+	if len(os.Args) < 3 {
 		showHelpAndExit("")
 	}
-	args := os.Args[4:]
+	// an artifact or the bytecode and the abi, none with -plan
+	files := os.Args[2:2]
+	for len(files) < 2 && 2+len(files) < len(os.Args) && !strings.HasPrefix(os.Args[2+len(files)], "-") {
+		files = os.Args[2 : 3+len(files)]
+	}
+	args := os.Args[2+len(files):]
 	var constructorArgs []string
 	for i, a := range args {
 		if a == "--" {
@@ -52,256 +181,231 @@ func main() {
 			break
 		}
 	}
-	fs := signerArgs.newFlagSet("args", flag.ExitOnError)
+	fs := newFlagSet()
 	if err := fs.Parse(args); err != nil {
 		internal.ErrorExit(-2, "invalid arguments: %s\n", err)
 	}
-	sigArgs, err := signerArgs.signatureArgs()
+	of, err := internal.ParseOutputFormat(outputFormat)
 	if err != nil {
-		internal.ErrorExit(-3, "can't parse arguments: %s\n", err)
+		internal.ErrorExit(-2, "invalid arguments: %s\n", err)
 	}
-	// read bytecode
-	bc, err := ioutil.ReadFile(os.Args[2])
-	if err != nil {
-		internal.ErrorExit(-4, "can't read bytecode: %s\n", err)
+	if verify {
+		receiptArgs.Wait = true
 	}
-	bytecode := make([]byte, len(bc)/2)
-	if _, err = hex.Decode(bytecode, bc); err != nil {
-		internal.ErrorExit(-5, "can't parse bytecode: %s\n", err)
+	if (planFile == "") == (len(files) == 0) {
+		showHelpAndExit("expecting an artifact or a plan")
+	}
+	if planFile != "" && (len(constructorArgs) > 0 || argsFile != "" || predict || create2 || unsignedOut != "" || signOnly) {
+		showHelpAndExit("constructor arguments, -args-file, -predict, -create2, -unsigned-out and -sign-only can't be used with -plan")
+	}
+	if verify && (unsignedOut != "" || signOnly) {
+		showHelpAndExit("-verify can't be used with -unsigned-out and -sign-only")
+	}
+	// dial client
+	cl, err := ethclient.Dial(os.Args[1])
+	if err != nil {
+		internal.ErrorExit(-10, "can't dial client: %s\n", err)
 	}
-	// parse abi
-	abi, err := internal.ReadABI(os.Args[3])
+	defer cl.Close()
+	chainID, err := cl.ChainID(context.Background())
 	if err != nil {
-		internal.ErrorExit(-6, "can't parse ABI: %s\n", err)
+		internal.ErrorExit(-11, "can't get chain id: %s\n", err)
 	}
-	if argsReq, argsProv := len(abi.Constructor.Inputs), len(constructorArgs); argsReq != argsProv {
-		internal.ErrorExit(-7, "expecting %d arguments for the constructor, only %d provided\n", argsReq, argsProv)
+	// resolve @name addresses with the address book of the chain
+	if err = internal.LoadAddressBook(chainID); err != nil {
+		fmt.Fprintf(os.Stderr, "address book disabled: %s\n", err)
 	}
-	cArgs := make([]interface{}, 0, len(constructorArgs))
-	for i, a := range constructorArgs {
-		b, err := json.Marshal(reflect.Zero(abi.Constructor.Inputs[i].Type.GetType()).Interface())
+	output := internal.NewPrinter(os.Stdout, of)
+	defer output.Close()
+	if planFile != "" {
+		sigArgs, err := signerArgs.SignatureArgs()
 		if err != nil {
-			internal.ErrorExit(
-				-8,
-				"can't marshal zero value for %s: %s\n",
-				abi.Constructor.Inputs[i].Type.String(),
-				err,
-			)
+			internal.ErrorExit(-3, "can't parse arguments: %s\n", err)
+		}
+		if sigArgs.Unsigned() {
+			internal.ErrorExit(-3, "plans need a signer\n")
 		}
-		if len(b) > 0 && b[0] == '"' {
-			a = `"` + a + `"`
+		if err = runPlan(cl, output, chainID, sigArgs, planFile, planOut); err != nil {
+			internal.ErrorExit(-15, "%s\n", err)
 		}
-		var rv reflect.Value
-		ty := abi.Constructor.Inputs[i].Type.GetType()
-		if ty.Kind() == reflect.Ptr {
-			rv = reflect.New(ty.Elem())
-		} else {
-			rv = reflect.New(ty)
+		return
+	}
+	// read the artifact or the bytecode and the abi
+	var artifact *internal.Artifact
+	if len(files) == 1 {
+		if artifact, err = internal.ReadArtifact(files[0]); err != nil {
+			internal.ErrorExit(-6, "can't read artifact: %s\n", err)
 		}
-		r := rv.Interface()
-		if err = json.Unmarshal([]byte(a), r); err != nil {
-			internal.ErrorExit(-9, "can't unmarshal constructor argument: %s\n", err)
+	} else {
+		if artifact, err = internal.ReadArtifact(files[1]); err != nil {
+			internal.ErrorExit(-6, "can't parse ABI: %s\n", err)
 		}
-		if ty.Kind() != reflect.Ptr {
-			cArgs = append(cArgs, rv.Elem().Interface())
-		} else {
-			cArgs = append(cArgs, r)
+		bc, err := ioutil.ReadFile(files[0])
+		if err != nil {
+			internal.ErrorExit(-4, "can't read bytecode: %s\n", err)
 		}
+		artifact.Bytecode = string(bc)
 	}
-	// dial client
-	cl, err := ethclient.Dial(os.Args[1])
+	libs, err := libraries.Libraries()
 	if err != nil {
-		internal.ErrorExit(-10, "can't dial client: %s\n", err)
+		internal.ErrorExit(-5, "%s\n", err)
 	}
-	defer cl.Close()
-	// deploy contract
-	chainID, err := cl.ChainID(context.Background())
+	unlinked, err := artifact.Link(libs)
 	if err != nil {
-		internal.ErrorExit(-11, "can't get chain id: %s\n", err)
+		internal.ErrorExit(-5, "can't link libraries: %s\n", err)
+	}
+	if len(unlinked) > 0 {
+		hint := "link them with -link name=address"
+		if h := internal.PlaceholderHint(unlinked); h != "" {
+			hint = h
+		}
+		internal.ErrorExit(-5, "unlinked libraries: %s (%s)\n", strings.Join(unlinked, ", "), hint)
 	}
-	addr, tx, _, err := bind.DeployContract(sigArgs.transactOpts(chainID), *abi, bytecode, cl, cArgs...)
+	bytecode, err := artifact.Code()
 	if err != nil {
-		internal.ErrorExit(-11, "can't deploy contract: %s\n", err)
+		internal.ErrorExit(-5, "can't parse bytecode: %s\n", err)
 	}
-	fmt.Printf("contract deployed to address %s\ntxid: %s\n", addr.Hex(), tx.Hash().Hex())
-}
-
-type signatureArgsParser struct {
-	rawKey         string
-	encKey         string
-	keyPassword    string
-	ledger         bool
-	derivationPath string
-	ledgerAddr     string
-	gasLimit       uint64
-	gasPrice       string
-	value          string
-}
-
-func (sap *signatureArgsParser) newFlagSet(name string, errorHandling flag.ErrorHandling) *flag.FlagSet {
-	fs := flag.NewFlagSet(name, errorHandling)
-	fs.StringVar(&sap.rawKey, "k", sap.rawKey, "raw key")
-	fs.StringVar(&sap.encKey, "e", sap.encKey, "encrypted key")
-	fs.StringVar(&sap.keyPassword, "P", sap.keyPassword, "password for the encrypted key")
-	fs.StringVar(&sap.gasPrice, "p", sap.gasPrice, "gas price")
-	fs.Uint64Var(&sap.gasLimit, "l", sap.gasLimit, "gas limit")
-	fs.StringVar(&sap.value, "v", sap.value, "value to send")
-	fs.BoolVar(&sap.ledger, "w", sap.ledger, "sign with ledger")
-	fs.StringVar(&sap.derivationPath, "d", sap.derivationPath, "derivation path")
-	fs.StringVar(&sap.ledgerAddr, "a", sap.ledgerAddr, "address (empty to use the first in the derivation path)")
-	return fs
-}
-
-var (
-	errInvalidGasPrice       = errors.New("invalid gas price")
-	errInvalidAmount         = errors.New("invalid amount")
-	errSignerMissing         = errors.New("signer arguments missing")
-	errSignerAddressNotfound = errors.New("signer address not found")
-	errLedgerNotFound        = errors.New("ledger not found")
-)
-
-type mutuallExclusiveArgsError [2]string
-
-func (e mutuallExclusiveArgsError) Error() string {
-	return fmt.Sprintf("%s and %s are mutually exclusive", e[0], e[1])
-}
-
-func (sap *signatureArgsParser) signatureArgs() (*signatureArgs, error) {
-	r := &signatureArgs{}
-	// parse gas price
-	bigZero := big.NewInt(0)
-	if sap.gasPrice != "" {
-		gp, ok := new(big.Int).SetString(sap.gasPrice, 10)
-		if !ok || gp.Cmp(bigZero) < 0 {
-			return nil, errInvalidGasPrice
-		} else if gp.Cmp(bigZero) > 0 {
-			r.gasPrice = gp
+	if _, err = internal.DecodeBytecode(artifact.DeployedBytecode); verify && !predict && err != nil {
+		internal.ErrorExit(-5, "can't verify without the deployed bytecode of an artifact: %s\n", err)
+	}
+	abi := artifact.ABI
+	var cArgs []interface{}
+	if argsFile != "" {
+		if len(constructorArgs) > 0 {
+			showHelpAndExit("constructor arguments can't be used with -args-file")
 		}
+		var v interface{}
+		if v, err = readArgsFile(argsFile); err != nil {
+			internal.ErrorExit(-7, "%s\n", err)
+		}
+		cArgs, err = internal.ConvertArguments(abi.Constructor.Inputs, v)
+	} else {
+		cArgs, err = internal.ParseArguments(abi.Constructor.Inputs, constructorArgs)
 	}
-	// parse gas limit
-	if sap.gasLimit > 0 {
-		r.gasLimit = sap.gasLimit
+	if err != nil {
+		internal.ErrorExit(-9, "invalid constructor arguments: %s\n", err)
 	}
-	if !sap.ledger && sap.rawKey == "" && sap.encKey == "" {
-		return nil, errSignerMissing
+	input, err := abi.Pack("", cArgs...)
+	if err != nil {
+		internal.ErrorExit(-8, "can't pack constructor arguments: %s\n", err)
 	}
-	// parse amount to send
-	if sap.value != "" {
-		v, ok := new(big.Int).SetString(sap.value, 10)
-		if !ok || v.Cmp(bigZero) < 0 {
-			return nil, errInvalidAmount
-		} else if v.Cmp(bigZero) > 0 {
-			r.value = v
+	initCode := append(bytecode, input...)
+	// predict the address, CREATE2 doesn't need a signer
+	var sigArgs *internal.SignatureArgs
+	if !predict || !create2 {
+		if sigArgs, err = signerArgs.SignatureArgs(); err != nil {
+			internal.ErrorExit(-3, "can't parse arguments: %s\n", err)
 		}
 	}
-	// sign with a key
-	if !sap.ledger {
-		if sap.rawKey != "" && sap.encKey != "" {
-			return nil, mutuallExclusiveArgsError{"-e", "-k"}
+	if predict {
+		pr, err := predictAddress(cl, sigArgs, initCode)
+		if err != nil {
+			internal.ErrorExit(-11, "can't predict address: %s\n", err)
+		}
+		if err = output.Print(pr); err != nil {
+			internal.ErrorExit(-12, "%s\n", err)
 		}
+		return
+	}
+	if sigArgs.Unsigned() && unsignedOut == "" {
+		internal.ErrorExit(-3, "-from can only be used with -unsigned-out or -predict\n")
+	}
+	// deploy contract
+	opts := sigArgs.TransactOpts(chainID)
+	if err = internal.FillFees(cl, opts, sigArgs.Legacy()); err != nil {
+		internal.ErrorExit(-11, "can't set transaction fees: %s\n", err)
+	}
+	if unsignedOut != "" {
+		// build the transaction without signing it
+		opts.Signer = internal.UnsignedSigner(chainID)
+	}
+	opts.NoSend = unsignedOut != "" || signOnly
+	var (
+		addr common.Address
+		tx   *types.Transaction
+		msg  = ethereum.CallMsg{From: opts.From, Value: opts.Value, Data: initCode}
+	)
+	if create2 {
 		var (
-			kb          []byte
-			kf          string
-			err         error
-			isEncrypted bool
+			factoryAddr common.Address
+			saltHash    common.Hash
+			code        []byte
 		)
-		if sap.rawKey != "" {
-			kf = sap.rawKey
-		} else {
-			isEncrypted = true
-			kf = sap.encKey
-			if sap.keyPassword == "" {
-				if sap.keyPassword, err = internal.PromptPassword(); err != nil {
-					return nil, err
+		if factoryAddr, err = internal.ResolveAddress(factory); err != nil {
+			internal.ErrorExit(-3, "invalid factory address: %s\n", err)
+		}
+		if saltHash, err = internal.ParseSalt(salt); err != nil {
+			internal.ErrorExit(-3, "%s\n", err)
+		}
+		addr = internal.Create2Address(factoryAddr, saltHash, initCode)
+		if code, err = cl.CodeAt(context.Background(), addr, nil); err != nil {
+			internal.ErrorExit(-11, "can't get code: %s\n", err)
+		}
+		if len(code) > 0 {
+			if err = output.Print(&internal.DeploymentRecord{Address: addr, Existing: true}); err != nil {
+				internal.ErrorExit(-12, "%s\n", err)
+			}
+			if verify {
+				if err = verifyDeployment(cl, output, artifact, addr); err != nil {
+					internal.ErrorExit(-16, "%s\n", err)
 				}
 			}
+			return
 		}
-		if kb, err = ioutil.ReadFile(kf); err != nil {
-			return nil, internal.WrapError("can't read file", err)
+		msg.To, msg.Data = &factoryAddr, internal.Create2Data(saltHash, initCode)
+		tx, err = internal.DeployCreate2(cl, opts, factoryAddr, saltHash, initCode)
+	} else {
+		addr, tx, _, err = bind.DeployContract(opts, *abi, bytecode, cl, cArgs...)
+	}
+	if err != nil {
+		internal.ErrorExit(-11, "can't deploy contract: %s\n", estimateError(cl, abi, opts, msg, err))
+	}
+	if opts.NoSend {
+		if unsignedOut != "" {
+			if err = internal.WriteTransaction(unsignedOut, tx, opts.From, chainID); err != nil {
+				internal.ErrorExit(-11, "%s\n", err)
+			}
 		}
-		key, err := internal.ParseKey(kb, isEncrypted, sap.keyPassword)
-		if err != nil {
-			return nil, err
+		rr := internal.NewRawTransactionRecord(tx, opts.From, chainID, unsignedOut)
+		rr.ContractAddress = &addr
+		if err = output.Print(rr); err != nil {
+			internal.ErrorExit(-12, "%s\n", err)
 		}
-		r.signer = signer.NewKeyed(key)
-		return r, nil
+		return
 	}
-	// sign with ledger
-	if sap.rawKey != "" {
-		return nil, mutuallExclusiveArgsError{"-w", "-k"}
+	txHash := tx.Hash()
+	if err = output.Print(&internal.DeploymentRecord{Address: addr, TxHash: &txHash}); err != nil {
+		internal.ErrorExit(-12, "%s\n", err)
 	}
-	if sap.encKey != "" {
-		return nil, mutuallExclusiveArgsError{"-w", "-e"}
+	if !receiptArgs.Wait {
+		return
 	}
-	if sap.keyPassword != "" {
-		return nil, mutuallExclusiveArgsError{"-w", "-P"}
+	// wait for the deployment
+	receipt, err := receiptArgs.WaitReceipt(cl, tx)
+	if err != nil {
+		internal.ErrorExit(-13, "%s\n", err)
 	}
-	// new hub
-	hub, err := usbwallet.NewLedgerHub()
+	rr, err := internal.NewReceiptRecord(cl, abi, addr, tx, receipt)
 	if err != nil {
-		return nil, internal.WrapError("can't open ledger hub", err)
-	}
-	// pick wallet
-	var w accounts.Wallet
-	if sz := len(hub.Wallets()); sz == 0 {
-		return nil, errLedgerNotFound
-	} else if sz != 1 {
-		return nil, internal.WrapError("more than one hardware wallet detected", nil)
-	}
-	w = hub.Wallets()[0]
-	// open
-	if err = w.Open(""); err != nil {
-		return nil, internal.WrapError("can't open wallet", err)
-	}
-	dp := strings.ReplaceAll(sap.derivationPath, "x", "%d")
-	if sap.ledgerAddr == "" {
-		acc, err := deriveAddress(w, dp, 0)
-		if err != nil {
-			return nil, internal.WrapError("can't derive address", err)
-		}
-		r.signer = signer.NewLedger(w, acc)
-		return r, nil
+		internal.ErrorExit(-13, "%s\n", err)
 	}
-	sigAddr := common.HexToAddress(sap.ledgerAddr)
-	for i := 0; i < 5; i++ {
-		acc, err := deriveAddress(w, dp, i)
-		if err != nil {
-			return nil, internal.WrapError("can't derive address", err)
-		}
-		if acc.Address == sigAddr {
-			r.signer = signer.NewLedger(w, acc)
-			return r, nil
-		}
+	if err = output.Print(rr); err != nil {
+		internal.ErrorExit(-12, "%s\n", err)
 	}
-	return nil, errSignerAddressNotfound
-}
-
-func deriveAddress(w accounts.Wallet, dp string, n int) (*accounts.Account, error) {
-	adp, err := accounts.ParseDerivationPath(fmt.Sprintf(dp, n))
-	if err != nil {
-		return nil, internal.WrapError("can't parse derivation path", err)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		internal.ErrorExit(-14, "deployment failed\n")
 	}
-	acc, err := w.Derive(adp, true)
-	if err != nil {
-		return nil, internal.WrapError("can't derive address", err)
+	if create2 {
+		// the factory doesn't fail if the deployment does
+		if code, err := cl.CodeAt(context.Background(), addr, nil); err != nil || len(code) == 0 {
+			internal.ErrorExit(-14, "deployment failed: no contract code after deployment\n")
+		}
+	} else if _, err = bind.WaitDeployed(context.Background(), cl, tx); err != nil {
+		internal.ErrorExit(-14, "deployment failed: %s\n", err)
 	}
-	return &acc, nil
-}
-
-type signatureArgs struct {
-	signer   *signer.Signer
-	gasPrice *big.Int
-	gasLimit uint64
-	value    *big.Int
-}
-
-func (sa *signatureArgs) transactOpts(chainID *big.Int) *bind.TransactOpts {
-	r, err := sa.signer.TransactOpts(chainID)
-	if err != nil {
-		panic(err)
+	if verify {
+		if err = verifyDeployment(cl, output, artifact, addr); err != nil {
+			internal.ErrorExit(-16, "%s\n", err)
+		}
 	}
-	r.GasLimit = sa.gasLimit
-	r.GasPrice = sa.gasPrice
-	r.Value = sa.value
-	return r
 }
@@ -0,0 +1,598 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/heliorosa/scui/internal"
+	"gopkg.in/yaml.v3"
+)
+
+const planExample = `
+  contracts:
+    - artifact: artifacts/Token.json   # or bytecode and abi, relative to the plan
+      args: ["Token", "TKN", 1000000]  # in order or by name
+    - name: Vault                      # the artifact name by default
+      artifact: artifacts/Vault.json
+      links: {Math: "0x..."}           # in addition to -link
+      args: {token: "${Token.address}"}
+      value: 1ether
+      calls:                           # sent after the deployment
+        - contract: Token              # the deployed contract by default
+          method: transfer
+          args: ["${Vault.address}", 1000]
+`
+
+// plan lists the contracts to deploy, in order. JSON plans are read as YAML
+type plan struct {
+	Contracts []*planContract `yaml:"contracts"`
+}
+
+type planContract struct {
+	// name used to reference the contract, the artifact name by default
+	Name string `yaml:"name"`
+	// an artifact or the bytecode and the abi
+	Artifact string `yaml:"artifact"`
+	Bytecode string `yaml:"bytecode"`
+	ABI      string `yaml:"abi"`
+	// constructor arguments in order or by name
+	Args  yaml.Node         `yaml:"args"`
+	Links map[string]string `yaml:"links"`
+	Value string            `yaml:"value"`
+	// calls made after the deployment
+	Calls []*planCall `yaml:"calls"`
+}
+
+type planCall struct {
+	// contract called, the deployed one by default
+	Contract string    `yaml:"contract"`
+	Method   string    `yaml:"method"`
+	Args     yaml.Node `yaml:"args"`
+	Value    string    `yaml:"value"`
+}
+
+// deployments is the result of a plan, and the state of a plan that failed
+// halfway to resume it
+type deployments struct {
+	ChainID   *big.Int               `json:"chainId"`
+	Contracts map[string]*deployment `json:"contracts"`
+	path      string
+}
+
+// txState is a transaction sent by a plan
+type txState struct {
+	Nonce  uint64      `json:"nonce"`
+	TxHash common.Hash `json:"txHash"`
+	// zero until the transaction is mined
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+}
+
+type deployment struct {
+	Address common.Address `json:"address"`
+	txState
+	// calls made after the deployment, in the order of the plan
+	Calls []*deploymentTx `json:"calls,omitempty"`
+	abi   *abi.ABI
+}
+
+type deploymentTx struct {
+	Contract string `json:"contract"`
+	Method   string `json:"method"`
+	txState
+}
+
+func readPlan(fn string) (*plan, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, internal.WrapError("can't read plan", err)
+	}
+	r := &plan{}
+	if err = yaml.Unmarshal(b, r); err != nil {
+		return nil, internal.WrapError("can't parse plan", err)
+	}
+	if len(r.Contracts) == 0 {
+		return nil, errors.New("plan has no contracts")
+	}
+	// files are relative to the plan
+	dir := filepath.Dir(fn)
+	for _, c := range r.Contracts {
+		for _, f := range []*string{&c.Artifact, &c.Bytecode, &c.ABI} {
+			if *f != "" && !filepath.IsAbs(*f) {
+				*f = filepath.Join(dir, *f)
+			}
+		}
+	}
+	return r, nil
+}
+
+var referenceRe = regexp.MustCompile(`\$\{([^}.]+)\.address\}`)
+
+// substitute replaces the ${Name.address} references to deployed contracts
+func (d *deployments) substitute(s string) (string, error) {
+	var err error
+	r := referenceRe.ReplaceAllStringFunc(s, func(m string) string {
+		name := referenceRe.FindStringSubmatch(m)[1]
+		dep, ok := d.Contracts[name]
+		if !ok {
+			err = fmt.Errorf("contract not deployed yet: %s", name)
+			return m
+		}
+		return dep.Address.Hex()
+	})
+	return r, err
+}
+
+// nodeValue converts a YAML node to the values taken by
+// internal.ConvertValue, substituting the references in strings. Numbers are
+// kept as text to not lose precision
+func (d *deployments) nodeValue(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case 0:
+		return nil, nil
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return d.nodeValue(n.Content[0])
+	case yaml.AliasNode:
+		return d.nodeValue(n.Alias)
+	case yaml.SequenceNode:
+		r := make([]interface{}, 0, len(n.Content))
+		for _, i := range n.Content {
+			v, err := d.nodeValue(i)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, v)
+		}
+		return r, nil
+	case yaml.MappingNode:
+		r := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v, err := d.nodeValue(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			r[n.Content[i].Value] = v
+		}
+		return r, nil
+	}
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		err := n.Decode(&b)
+		return b, err
+	case "!!int":
+		// YAML reads unquoted hex as integers, but they can also be bytes
+		// or addresses: the string lets the ABI type decide
+		if strings.HasPrefix(n.Value, "0x") || strings.HasPrefix(n.Value, "0X") {
+			break
+		}
+		// other bases and _ separators, as in 0o17 or 1_000, are read as
+		// YAML does, in base 10 for ConvertValue
+		r, ok := new(big.Int).SetString(strings.ReplaceAll(n.Value, "_", ""), 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer at line %d: %s", n.Line, n.Value)
+		}
+		return json.Number(r.String()), nil
+	case "!!float":
+		return json.Number(n.Value), nil
+	}
+	return d.substitute(n.Value)
+}
+
+// libraries returns the libraries of c, with the flags as defaults
+func (d *deployments) libraries(c *planContract) (internal.Libraries, error) {
+	r, err := libraries.Libraries()
+	if err != nil {
+		return nil, err
+	}
+	for name, a := range c.Links {
+		if a, err = d.substitute(a); err != nil {
+			return nil, err
+		}
+		if r[name], err = internal.ResolveAddress(a); err != nil {
+			return nil, internal.WrapError("invalid address for library "+name, err)
+		}
+	}
+	return r, nil
+}
+
+// readDeployments reads the deployments left by a previous run of a plan
+func readDeployments(fn string, chainID *big.Int) (*deployments, error) {
+	r := &deployments{ChainID: chainID, Contracts: make(map[string]*deployment), path: fn}
+	b, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, internal.WrapError("can't read deployments", err)
+	}
+	if err = json.Unmarshal(b, r); err != nil {
+		return nil, internal.WrapError("can't parse deployments", err)
+	}
+	if r.ChainID == nil || r.ChainID.Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("deployments in %s are from chain %v, not %v", fn, r.ChainID, chainID)
+	}
+	if r.Contracts == nil {
+		r.Contracts = make(map[string]*deployment)
+	}
+	return r, nil
+}
+
+// save writes the deployments, replacing the file only when it's complete
+func (d *deployments) save() error {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := d.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return internal.WrapError("can't write deployments", err)
+	}
+	if err = os.Rename(tmp, d.path); err != nil {
+		return internal.WrapError("can't write deployments", err)
+	}
+	return nil
+}
+
+// resume returns the transaction of st recorded by a previous run, nil if it
+// has to be sent again. That's only when it failed or when it isn't known and
+// its nonce is still free, otherwise it was replaced or is pending somewhere
+// else and sending it again would repeat the step
+func (d *deployments) resume(cl *ethclient.Client, from common.Address, st *txState) (*types.Transaction, error) {
+	if st.TxHash == (common.Hash{}) {
+		return nil, nil
+	}
+	ctx := context.Background()
+	tx, _, err := cl.TransactionByHash(ctx, st.TxHash)
+	if errors.Is(err, ethereum.NotFound) {
+		confirmed, err := cl.NonceAt(ctx, from, nil)
+		if err != nil {
+			return nil, internal.WrapError("can't get nonce", err)
+		}
+		pending, err := cl.PendingNonceAt(ctx, from)
+		if err != nil {
+			return nil, internal.WrapError("can't get nonce", err)
+		}
+		switch {
+		case confirmed > st.Nonce:
+			return nil, fmt.Errorf("transaction %s isn't known and its nonce %d was mined, it was replaced; check the step and remove it from %s to send it again", st.TxHash.Hex(), st.Nonce, d.path)
+		case pending > st.Nonce:
+			return nil, fmt.Errorf("transaction %s isn't known and its nonce %d is used by a pending transaction, it was replaced; wait for it or remove the step from %s to send it again", st.TxHash.Hex(), st.Nonce, d.path)
+		}
+		fmt.Fprintf(os.Stderr, "transaction %s was dropped, sending it again\n", st.TxHash.Hex())
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internal.WrapError("can't get transaction", err)
+	}
+	r, err := cl.TransactionReceipt(ctx, st.TxHash)
+	switch {
+	case errors.Is(err, ethereum.NotFound):
+		// still pending
+		return tx, nil
+	case err != nil:
+		return nil, internal.WrapError("can't get receipt", err)
+	case r.Status != types.ReceiptStatusSuccessful:
+		fmt.Fprintf(os.Stderr, "transaction %s failed, sending it again\n", st.TxHash.Hex())
+		return nil, nil
+	}
+	return tx, nil
+}
+
+// send broadcasts tx, which is recorded in the deployments before to not
+// send the step again if this run stops
+func send(cl *ethclient.Client, tx *types.Transaction) error {
+	if err := cl.SendTransaction(context.Background(), tx); err != nil {
+		return internal.WrapError("can't send transaction", err)
+	}
+	return nil
+}
+
+// planArtifact reads the artifact or the bytecode and the abi of c
+func planArtifact(c *planContract) (*internal.Artifact, error) {
+	if c.Artifact != "" {
+		return internal.ReadArtifact(c.Artifact)
+	}
+	if c.Bytecode == "" || c.ABI == "" {
+		return nil, errors.New("expecting an artifact or the bytecode and the abi")
+	}
+	r, err := internal.ReadArtifact(c.ABI)
+	if err != nil {
+		return nil, err
+	}
+	bc, err := ioutil.ReadFile(c.Bytecode)
+	if err != nil {
+		return nil, internal.WrapError("can't read bytecode", err)
+	}
+	r.Bytecode = string(bc)
+	return r, nil
+}
+
+// transactOpts returns new options with the nonce, the fees and value set.
+// The transactions are only signed, to record them before sending them
+func (d *deployments) transactOpts(cl *ethclient.Client, sigArgs *internal.SignatureArgs, value string) (*bind.TransactOpts, error) {
+	opts := sigArgs.TransactOpts(d.ChainID)
+	opts.NoSend = true
+	n, err := cl.PendingNonceAt(context.Background(), opts.From)
+	if err != nil {
+		return nil, internal.WrapError("can't get nonce", err)
+	}
+	opts.Nonce = new(big.Int).SetUint64(n)
+	if value != "" {
+		v, err := internal.ParseAmount(value)
+		if err != nil {
+			return nil, err
+		}
+		opts.Value = v
+	}
+	if err := internal.FillFees(cl, opts, sigArgs.Legacy()); err != nil {
+		return nil, internal.WrapError("can't set transaction fees", err)
+	}
+	return opts, nil
+}
+
+// waitSuccess waits for the receipt of tx and fails if it was reverted
+func waitSuccess(cl *ethclient.Client, output *internal.Printer, contractABI *abi.ABI, addr common.Address, tx *types.Transaction) (*types.Receipt, error) {
+	r, err := receiptArgs.WaitReceipt(cl, tx)
+	if err != nil {
+		return nil, err
+	}
+	if receiptArgs.Wait || r.Status != types.ReceiptStatusSuccessful {
+		rr, err := internal.NewReceiptRecord(cl, contractABI, addr, tx, r)
+		if err != nil {
+			return nil, err
+		}
+		if err = output.Print(rr); err != nil {
+			return nil, err
+		}
+	}
+	if r.Status != types.ReceiptStatusSuccessful {
+		return nil, errors.New("transaction failed")
+	}
+	return r, nil
+}
+
+// runPlan deploys the contracts of the plan in fn and writes the deployments
+// to out after each transaction. The transactions recorded in out by a
+// previous run aren't sent again
+func runPlan(cl *ethclient.Client, output *internal.Printer, chainID *big.Int, sigArgs *internal.SignatureArgs, fn string, out string) error {
+	p, err := readPlan(fn)
+	if err != nil {
+		return err
+	}
+	d, err := readDeployments(out, chainID)
+	if err != nil {
+		return err
+	}
+	artifacts := make([]*internal.Artifact, 0, len(p.Contracts))
+	names := make(map[string]struct{}, len(p.Contracts))
+	for i, c := range p.Contracts {
+		artifact, err := planArtifact(c)
+		if err != nil {
+			return internal.WrapError(fmt.Sprintf("contract %d", i), err)
+		}
+		if c.Name == "" {
+			c.Name = artifact.Name
+		}
+		if _, ok := names[c.Name]; ok {
+			return fmt.Errorf("contract %s is deployed twice, give them different names", c.Name)
+		}
+		names[c.Name] = struct{}{}
+		artifacts = append(artifacts, artifact)
+	}
+	// the calls can use the contracts deployed by a previous run
+	for i, c := range p.Contracts {
+		if dep, ok := d.Contracts[c.Name]; ok {
+			dep.abi = artifacts[i].ABI
+		}
+	}
+	for i, c := range p.Contracts {
+		if err = d.deploy(cl, output, sigArgs, c, artifacts[i]); err != nil {
+			return internal.WrapError("can't deploy "+c.Name, err)
+		}
+		for j, call := range c.Calls {
+			if err = d.call(cl, output, sigArgs, c.Name, j, call); err != nil {
+				return internal.WrapError(fmt.Sprintf("can't call %s after deploying %s", call.Method, c.Name), err)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *deployments) deploy(cl *ethclient.Client, output *internal.Printer, sigArgs *internal.SignatureArgs, c *planContract, artifact *internal.Artifact) error {
+	libs, err := d.libraries(c)
+	if err != nil {
+		return err
+	}
+	unlinked, err := artifact.Link(libs)
+	if err != nil {
+		return internal.WrapError("can't link libraries", err)
+	}
+	if len(unlinked) > 0 {
+		if h := internal.PlaceholderHint(unlinked); h != "" {
+			return fmt.Errorf("unlinked libraries: %s (%s)", strings.Join(unlinked, ", "), h)
+		}
+		return fmt.Errorf("unlinked libraries: %s", strings.Join(unlinked, ", "))
+	}
+	if _, err = internal.DecodeBytecode(artifact.DeployedBytecode); verify && err != nil {
+		return internal.WrapError("can't verify without the deployed bytecode of an artifact", err)
+	}
+	dep := d.Contracts[c.Name]
+	if dep != nil && dep.BlockNumber > 0 {
+		dep.abi = artifact.ABI
+		if err = output.Print(&internal.DeploymentRecord{Address: dep.Address, Existing: true}); err != nil {
+			return err
+		}
+		if verify {
+			return verifyDeployment(cl, output, artifact, dep.Address)
+		}
+		return nil
+	}
+	bytecode, err := artifact.Code()
+	if err != nil {
+		return err
+	}
+	args, err := d.nodeValue(&c.Args)
+	if err != nil {
+		return err
+	}
+	cArgs, err := internal.ConvertArguments(artifact.ABI.Constructor.Inputs, args)
+	if err != nil {
+		return internal.WrapError("invalid constructor arguments", err)
+	}
+	value, err := d.substitute(c.Value)
+	if err != nil {
+		return err
+	}
+	var tx *types.Transaction
+	if dep != nil {
+		if tx, err = d.resume(cl, sigArgs.From(), &dep.txState); err != nil {
+			return err
+		}
+	}
+	if tx == nil {
+		opts, err := d.transactOpts(cl, sigArgs, value)
+		if err != nil {
+			return err
+		}
+		addr, sent, _, err := bind.DeployContract(opts, *artifact.ABI, bytecode, cl, cArgs...)
+		if err != nil {
+			if input, perr := artifact.ABI.Pack("", cArgs...); perr == nil {
+				err = estimateError(cl, artifact.ABI, opts, ethereum.CallMsg{From: opts.From, Value: opts.Value, Data: append(bytecode, input...)}, err)
+			}
+			return err
+		}
+		tx = sent
+		dep = &deployment{Address: addr, txState: txState{Nonce: tx.Nonce(), TxHash: tx.Hash()}}
+		d.Contracts[c.Name] = dep
+		if err = d.save(); err != nil {
+			return err
+		}
+		if err = send(cl, tx); err != nil {
+			return err
+		}
+	}
+	dep.abi = artifact.ABI
+	txHash := tx.Hash()
+	if err = output.Print(&internal.DeploymentRecord{Address: dep.Address, TxHash: &txHash}); err != nil {
+		return err
+	}
+	r, err := waitSuccess(cl, output, artifact.ABI, dep.Address, tx)
+	if err != nil {
+		return err
+	}
+	dep.BlockNumber = r.BlockNumber.Uint64()
+	if err = d.save(); err != nil {
+		return err
+	}
+	if verify {
+		return verifyDeployment(cl, output, artifact, dep.Address)
+	}
+	return nil
+}
+
+// call sends the call n made after deploying the contract deployed
+func (d *deployments) call(cl *ethclient.Client, output *internal.Printer, sigArgs *internal.SignatureArgs, deployed string, n int, call *planCall) error {
+	if call.Contract == "" {
+		call.Contract = deployed
+	}
+	dep, ok := d.Contracts[call.Contract]
+	if !ok || dep.BlockNumber == 0 {
+		return fmt.Errorf("contract not deployed yet: %s", call.Contract)
+	}
+	// the deployments can have contracts of other plans
+	if dep.abi == nil {
+		return fmt.Errorf("no abi for %s, add it to the plan", call.Contract)
+	}
+	method, ok := dep.abi.Methods[call.Method]
+	if !ok {
+		return fmt.Errorf("method not found: %s", call.Method)
+	}
+	// the state of the call, if a previous run sent it
+	calls := &d.Contracts[deployed].Calls
+	var st *deploymentTx
+	if n < len(*calls) {
+		st = (*calls)[n]
+		if st.Contract != call.Contract || st.Method != method.Name {
+			return fmt.Errorf("deployments in %s don't match the plan, call %d was %s.%s", d.path, n, st.Contract, st.Method)
+		}
+		if st.BlockNumber > 0 {
+			fmt.Fprintf(os.Stderr, "%s.%s already sent in transaction %s, skipping\n", st.Contract, st.Method, st.TxHash.Hex())
+			return nil
+		}
+	}
+	args, err := d.nodeValue(&call.Args)
+	if err != nil {
+		return err
+	}
+	mArgs, err := internal.ConvertArguments(method.Inputs, args)
+	if err != nil {
+		return internal.WrapError("invalid arguments", err)
+	}
+	value, err := d.substitute(call.Value)
+	if err != nil {
+		return err
+	}
+	var tx *types.Transaction
+	if st != nil {
+		if tx, err = d.resume(cl, sigArgs.From(), &st.txState); err != nil {
+			return err
+		}
+	}
+	if tx == nil {
+		opts, err := d.transactOpts(cl, sigArgs, value)
+		if err != nil {
+			return err
+		}
+		bc := bind.NewBoundContract(dep.Address, *dep.abi, cl, cl, cl)
+		if tx, err = bc.Transact(opts, method.Name, mArgs...); err != nil {
+			if input, perr := dep.abi.Pack(method.Name, mArgs...); perr == nil {
+				err = estimateError(cl, dep.abi, opts, ethereum.CallMsg{From: opts.From, To: &dep.Address, Value: opts.Value, Data: input}, err)
+			}
+			return err
+		}
+		st = &deploymentTx{
+			Contract: call.Contract,
+			Method:   method.Name,
+			txState:  txState{Nonce: tx.Nonce(), TxHash: tx.Hash()},
+		}
+		if n < len(*calls) {
+			(*calls)[n] = st
+		} else {
+			*calls = append(*calls, st)
+		}
+		if err = d.save(); err != nil {
+			return err
+		}
+		if err = send(cl, tx); err != nil {
+			return err
+		}
+	}
+	if err = output.Print(&internal.TransactionRecord{Method: call.Contract + "." + method.Name, TxHash: tx.Hash()}); err != nil {
+		return err
+	}
+	r, err := waitSuccess(cl, output, dep.abi, dep.Address, tx)
+	if err != nil {
+		return err
+	}
+	st.BlockNumber = r.BlockNumber.Uint64()
+	return d.save()
+}
@@ -2,160 +2,171 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"os"
 	"os/signal"
 	"reflect"
-	"strings"
 	"syscall"
 
 	"github.com/c-bata/go-prompt"
-	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/heliorosa/scui/internal"
 	"github.com/heliorosa/scui/signer"
 	"github.com/heliorosa/scui/ui"
 )
 
 var menuCommands = map[string]func(){
-	"signer/key":    cmdConfigSignerKey,
-	"signer/ledger": cmdConfigSignerLedger,
-	"signer/show":   cmdConfigSignerShow,
+	"signer/show": cmdConfigSignerShow,
+
+	"contracts/add":    cmdContractsAdd,
+	"contracts/remove": cmdContractsRemove,
+	"contracts/list":   cmdContractsList,
+	"contracts/use":    cmdContractsUse,
+
+	"addressbook/add":    cmdAddressBookAdd,
+	"addressbook/remove": cmdAddressBookRemove,
+	"addressbook/list":   cmdAddressBookList,
 }
 
-func cmdConfigSignerKey() {
-	key, err := inputKeyFile()
-	if err != nil {
-		fmt.Printf("can't read key file: %s\n", err)
-		return
+// a menu command for each signer backend
+func init() {
+	for _, b := range signer.NewBackends() {
+		b := b
+		menuCommands["signer/"+b.Name()] = func() { cmdConfigSigner(b) }
 	}
-	txSigner = signer.NewKeyed(key)
 }
 
-func cmdConfigSignerLedger() {
+func cmdConfigSigner(b signer.Backend) {
+	s, err := b.FromPrompt(internal.SignerEnv, uiPrompter{})
+	if err != nil {
+		fmt.Printf("can't configure signer: %s\n", err)
+		return
+	}
 	// close any previously open wallet
-	if txSigner != nil &&
-		txSigner.Kind() == signer.HardwareWallet &&
-		txSigner.Wallet != nil {
-		txSigner.Wallet.Wallet.Close()
+	if txSigner != nil {
+		signer.Close(txSigner)
 	}
-	// new hub
-	hub, err := usbwallet.NewLedgerHub()
-	if err != nil {
-		fmt.Printf("can't start hub: %s\n", err)
+	txSigner = s
+}
+
+func cmdConfigSignerShow() {
+	if txSigner == nil {
+		fmt.Printf("no signer set\n")
 		return
 	}
-	// pick wallet
-	var w accounts.Wallet
-	if sz := len(hub.Wallets()); sz == 0 {
-		fmt.Printf("can't find any wallets\n")
+	fmt.Printf("sign with %s.\naddress: %s\n", txSigner.Describe(), txSigner.Address().Hex())
+}
+
+func cmdContractsAdd() {
+	c, err := inputContract()
+	if err != nil {
+		fmt.Printf("can't add contract: %s\n", err)
 		return
-	} else if sz == 1 {
-		w = hub.Wallets()[0]
-	} else {
-		fmt.Printf("found more than one wallet. choose one.\n")
-		wallets := hub.Wallets()
-		wm := make(map[string]accounts.Wallet, len(wallets))
-		wu := make([]string, 0, len(wallets))
-		for _, i := range wallets {
-			wm[i.URL().String()] = i
-			wu = append(wu, i.URL().String())
-		}
-		u, ok := ui.InputMultiChoiceString("wallet (%s): ", wu[0], wu, func(c []prompt.Suggest) {
-			fmt.Printf("choose one of the url's\n")
-		})
-		if !ok {
-			fmt.Printf("aborted\n")
-			return
-		}
-		w = wm[u]
 	}
-	// open
-	if err = w.Open(""); err != nil {
-		fmt.Printf("can't open wallet: %s\n", err)
+	if err = contracts.add(c); err != nil {
+		fmt.Printf("can't add contract: %s\n", err)
 		return
 	}
-	// check status
-	st, err := w.Status()
-	if err != nil {
-		fmt.Printf("can't get status: %s\n", err)
+	if use, ok := ui.InputYesNo("use it now? (%s): ", true); ok && use {
+		if err = contracts.use(c.name); err != nil {
+			fmt.Printf("can't use contract: %s\n", err)
+		}
 	}
-	fmt.Printf("wallet status: %s\n", st)
-	dpTemplates := []string{"m/44'/60'/x'/0/0", "m/44'/60'/0'/x", "custom"}
-	dp, ok := ui.InputMultiChoiceString("derivation path (%s): ", dpTemplates[0], dpTemplates, func(c []prompt.Suggest) {
-		fmt.Printf("choose one of the derivation paths or custom\n")
+}
+
+// inputContractName asks for the name of one of the contracts
+func inputContractName() (string, bool) {
+	return ui.InputMultiChoiceString("contract (%s): ", contracts.active.name, contracts.names(), func(c []prompt.Suggest) {
+		for _, i := range c {
+			fmt.Printf("%s\n", i.Text)
+		}
 	})
+}
+
+func cmdContractsRemove() {
+	name, ok := inputContractName()
 	if !ok {
-		fmt.Printf("aborted\n")
 		return
 	}
-	if dp == "custom" {
-		dp = ui.InputText("custom derivation path: ")
+	if err := contracts.remove(name); err != nil {
+		fmt.Printf("can't remove contract: %s\n", err)
 	}
-	dp = strings.Replace(dp, "x", "%d", -1)
-	// pick address
-	addrs := make([]string, 0, 5)
-	for {
-		accs := make(map[string]*accounts.Account, 5)
-		for i := 0; i < 5; i++ {
-			dp, err := accounts.ParseDerivationPath(fmt.Sprintf(dp, i))
-			if err != nil {
-				fmt.Printf("can't parse derivation path: %s\n", err)
-				return
-			}
-			acc, err := w.Derive(dp, true)
-			if err != nil {
-				fmt.Printf("can't derive address: %s\n", err)
-				return
-			}
-			addrs = append(addrs, acc.Address.Hex())
-			accs[acc.Address.Hex()] = &acc
-		}
-		choices := append(make([]string, 0, len(addrs)+1), addrs...)
-		choices = append(choices, "more")
-		a, ok := ui.InputMultiChoiceString("address (%s): ", addrs[0], choices, func(c []prompt.Suggest) {
-			fmt.Printf("pick one address or more to generate\n")
-		})
-		if !ok {
-			fmt.Printf("aborted\n")
-			return
-		}
-		if a == "more" {
-			continue
+}
+
+func cmdContractsList() {
+	for _, i := range contracts.names() {
+		c := contracts.contracts[i]
+		mark := " "
+		if c == contracts.active {
+			mark = "*"
 		}
-		txSigner = signer.NewLedger(w, accs[a])
-		break
+		fmt.Printf("%s %s %s %s\n", mark, c.name, c.addr.Hex(), c.abiFile)
 	}
 }
 
-func cmdConfigSignerShow() {
-	if sk := txSigner.Kind(); sk == signer.None {
-		fmt.Printf("no signer set\n")
+func cmdContractsUse() {
+	name, ok := inputContractName()
+	if !ok {
 		return
-	} else if sk == signer.Keyed {
-		fmt.Printf(
-			"sign with a key file.\naddress: %s\n",
-			crypto.PubkeyToAddress(txSigner.Key.PublicKey).Hex(),
-		)
+	}
+	if err := contracts.use(name); err != nil {
+		fmt.Printf("can't use contract: %s\n", err)
+	}
+}
+
+var errNoAddressBook = errors.New("address book not available")
+
+func cmdAddressBookAdd() {
+	if internal.Addresses == nil {
+		fmt.Printf("%s\n", errNoAddressBook)
 		return
 	}
-	st, err := txSigner.Wallet.Wallet.Status()
+	name := ui.InputText("name: ")
+	if name == "" || name == ".." {
+		fmt.Printf("aborted\n")
+		return
+	}
+	addr, err := inputAddress("address", nil)
 	if err != nil {
-		fmt.Printf("can't get hardware wallet status: %s\n", err)
+		fmt.Printf("%s\n", err)
+		return
+	}
+	if err = internal.Addresses.Set(name, addr); err != nil {
+		fmt.Printf("can't add address: %s\n", err)
+	}
+}
+
+func cmdAddressBookRemove() {
+	names := internal.Addresses.Names()
+	if len(names) == 0 {
+		fmt.Printf("address book is empty\n")
 		return
 	}
-	fmt.Printf(
-		"sign with hardware wallet.\n%s\naddress: %s\n",
-		st,
-		txSigner.Wallet.Account.Address.Hex(),
-	)
+	name, ok := ui.InputMultiChoiceString("name (%s): ", names[0], names, func(c []prompt.Suggest) {
+		for _, i := range c {
+			fmt.Printf("%s\n", i.Text)
+		}
+	})
+	if !ok {
+		return
+	}
+	if err := internal.Addresses.Remove(name); err != nil {
+		fmt.Printf("can't remove address: %s\n", err)
+	}
+}
+
+func cmdAddressBookList() {
+	for _, i := range internal.Addresses.Names() {
+		a, _ := internal.Addresses.Lookup(i)
+		fmt.Printf("@%s %s\n", i, a.Hex())
+	}
 }
 
 var (
@@ -165,12 +176,7 @@ var (
 	errNoSigner    = errors.New("no configured signer")
 )
 
-func executeConstantMethod(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string) ([]interface{}, error) {
-	fmt.Printf("constant call arguments:\n")
-	args, err := inputArguments(abi.Methods[name].Inputs)
-	if err != nil {
-		return nil, err
-	}
+func executeConstantMethod(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string, args []interface{}) ([]interface{}, error) {
 	bc := bind.NewBoundContract(*addr, *abi, cl, cl, cl)
 	method := abi.Methods[name]
 	if !method.IsConstant() {
@@ -179,11 +185,17 @@ func executeConstantMethod(cl *ethclient.Client, addr *common.Address, abi *abi.
 	// call method
 	res := newCallResult(method.Outputs)
 	if err := bc.Call(nil, &res.res, name, args...); err != nil {
-		return nil, err
+		return nil, internal.WrapRevert(abi, err)
 	}
 	return res.results(), nil
 }
 
+func printRecord(r internal.Record) {
+	if err := output.Print(r); err != nil {
+		fmt.Printf("can't print result: %s\n", err)
+	}
+}
+
 type callResult struct {
 	mo  abi.Arguments
 	res []interface{}
@@ -192,7 +204,7 @@ type callResult struct {
 func newCallResult(mo abi.Arguments) *callResult {
 	switch len(mo) {
 	case 0:
-		return nil
+		return &callResult{}
 	case 1:
 		return &callResult{mo: mo, res: []interface{}{reflect.New(mo[0].Type.GetType()).Interface()}}
 	default:
@@ -210,6 +222,9 @@ func indirectInterface(v interface{}) interface{} {
 
 func (cr *callResult) results() []interface{} {
 	r := make([]interface{}, 0, 4)
+	if len(cr.res) == 0 {
+		return r
+	}
 	var rr []interface{}
 	if res, ok := cr.res[0].(*[]interface{}); ok {
 		rr = *res
@@ -222,29 +237,16 @@ func (cr *callResult) results() []interface{} {
 	return r
 }
 
-func executeTransactMethod(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string) (*types.Transaction, error) {
-	if txSigner == nil || txSigner.Kind() == signer.None {
+func inputTransactOpts(cl *ethclient.Client, abi *abi.ABI, name string) (*bind.TransactOpts, error) {
+	if txSigner == nil {
 		fmt.Printf("can't execute transact method without a configured signer\n")
 		return nil, errNoSigner
 	}
-	method := abi.Methods[name]
-	if method.IsConstant() {
-		return nil, errConstant
-	}
-	fmt.Printf("transaction arguments:\n")
-	args, err := inputArguments(abi.Methods[name].Inputs)
-	if err != nil {
-		return nil, err
-	}
-	bc := bind.NewBoundContract(*addr, *abi, cl, cl, cl)
 	chainID, err := cl.ChainID(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	opts, err := txSigner.TransactOpts(chainID)
-	if err != nil {
-		return nil, err
-	}
+	opts := signer.TransactOpts(txSigner, chainID)
 	if abi.Methods[name].IsPayable() {
 		send, ok := ui.InputYesNo("method is payable. send amount with transaction? (%s): ", false)
 		if !ok {
@@ -255,14 +257,47 @@ func executeTransactMethod(cl *ethclient.Client, addr *common.Address, abi *abi.
 			opts.Value = amount
 		}
 	}
-	if estimateGasPrice, ok := ui.InputYesNo("estimate gas price? (%s): ", true); !ok {
-		return nil, errAborted
-	} else if !estimateGasPrice {
+	// some wallets only sign legacy transactions, and chains without London
+	// only take them
+	legacy := signer.LegacyOnly(txSigner)
+	if !legacy {
+		dynamic, err := internal.DynamicFees(cl)
+		if err != nil {
+			return nil, err
+		}
+		legacy = !dynamic
+	}
+	if !legacy {
+		var ok bool
+		if legacy, ok = ui.InputYesNo("send a legacy transaction? (%s): ", false); !ok {
+			return nil, errAborted
+		}
+	}
+	if legacy {
 		sugg, err := cl.SuggestGasPrice(context.Background())
 		if err != nil {
 			return nil, err
 		}
-		opts.GasPrice = ui.InputBigIntWithDefault("gas price (%s): ", sugg)
+		if estimateGasPrice, ok := ui.InputYesNo("estimate gas price? (%s): ", true); !ok {
+			return nil, errAborted
+		} else if estimateGasPrice {
+			opts.GasPrice = sugg
+		} else {
+			opts.GasPrice = ui.InputBigIntWithDefault("gas price (%s): ", sugg)
+		}
+	} else {
+		tipCap, feeCap, err := internal.SuggestFees(cl)
+		if err != nil {
+			return nil, err
+		}
+		if estimateFees, ok := ui.InputYesNo("estimate fees? (%s): ", true); !ok {
+			return nil, errAborted
+		} else if estimateFees {
+			opts.GasTipCap, opts.GasFeeCap = tipCap, feeCap
+		} else {
+			opts.GasTipCap = ui.InputBigIntWithDefault("max priority fee per gas (%s): ", tipCap)
+			opts.GasFeeCap = ui.InputBigIntWithDefault("max fee per gas (%s): ", feeCap)
+		}
 	}
 	if estimateGasLimit, ok := ui.InputYesNo("estimate gas limit? (%s): ", true); !ok {
 		return nil, errAborted
@@ -271,72 +306,204 @@ func executeTransactMethod(cl *ethclient.Client, addr *common.Address, abi *abi.
 			opts.GasLimit = uint64(gl)
 		}
 	}
-	return bc.Transact(opts, name, args...)
+	return opts, nil
 }
 
-func listEvents(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string) {
-	filters, err := inputFilters(abi.Events[name].Inputs)
+// waitReceipt waits for the receipt of tx and decodes the events emitted by
+// the contract at addr
+func waitReceipt(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, tx *types.Transaction) (*internal.ReceiptRecord, error) {
+	r, err := receiptArgs.WaitReceipt(cl, tx)
 	if err != nil {
-		fmt.Printf("error parsing filter fields: %s\n", err)
-		return
+		return nil, err
+	}
+	return internal.NewReceiptRecord(cl, abi, *addr, tx, r)
+}
+
+// simulateTransaction runs the transaction with eth_call from the signer
+// address and estimates the gas it needs
+func simulateTransaction(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string, opts *bind.TransactOpts, args []interface{}) (*internal.SimulationRecord, error) {
+	method := abi.Methods[name]
+	input, err := abi.Pack(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	msg := ethereum.CallMsg{From: opts.From, To: addr, Value: opts.Value, Data: input}
+	r := &internal.SimulationRecord{Method: name, Args: method.Outputs}
+	out, err := cl.CallContract(context.Background(), msg, nil)
+	if err != nil {
+		var re *internal.RevertError
+		if !errors.As(internal.WrapRevert(abi, err), &re) {
+			return nil, err
+		}
+		r.Reverted = true
+		r.RevertReason = re.Reason
+		return r, nil
+	}
+	if r.Results, err = method.Outputs.Unpack(out); err != nil {
+		return nil, internal.WrapError("can't decode returned values", err)
+	}
+	if r.GasEstimate, err = cl.EstimateGas(context.Background(), msg); err != nil {
+		return nil, internal.WrapError("can't estimate gas", err)
+	}
+	return r, nil
+}
+
+// inputSimulation offers to simulate the transaction and asks whether to
+// send it after showing the outcome
+func inputSimulation(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string, opts *bind.TransactOpts, args []interface{}) bool {
+	simulate, ok := ui.InputYesNo("simulate transaction? (%s): ", true)
+	if !ok {
+		return false
+	}
+	if !simulate {
+		return true
+	}
+	sr, err := simulateTransaction(cl, addr, abi, name, opts, args)
+	if err != nil {
+		fmt.Printf("can't simulate transaction: %s\n", err)
+		return false
+	}
+	printRecord(sr)
+	send, ok := ui.InputYesNo("send transaction? (%s): ", !sr.Reverted)
+	return ok && send
+}
+
+// prepareTransaction fills the nonce and the gas limit of opts if they
+// weren't set and returns the summary of the transaction
+func prepareTransaction(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string, opts *bind.TransactOpts, args []interface{}) (*internal.TransactionSummary, error) {
+	method := abi.Methods[name]
+	input, err := abi.Pack(name, args...)
+	if err != nil {
+		return nil, err
 	}
+	ctx := context.Background()
+	chainID, err := cl.ChainID(ctx)
+	if err != nil {
+		return nil, internal.WrapError("can't get chain id", err)
+	}
+	if opts.Nonce == nil {
+		nonce, err := cl.PendingNonceAt(ctx, opts.From)
+		if err != nil {
+			return nil, internal.WrapError("can't get nonce", err)
+		}
+		opts.Nonce = new(big.Int).SetUint64(nonce)
+	}
+	if opts.GasLimit == 0 {
+		if opts.GasLimit, err = internal.EstimateGas(cl, abi, ethereum.CallMsg{
+			From:  opts.From,
+			To:    addr,
+			Value: opts.Value,
+			Data:  input,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return &internal.TransactionSummary{
+		From:      opts.From,
+		To:        addr,
+		ChainID:   chainID,
+		Method:    method.Sig,
+		Args:      method.Inputs,
+		Values:    args,
+		Value:     opts.Value,
+		Nonce:     opts.Nonce.Uint64(),
+		GasLimit:  opts.GasLimit,
+		GasPrice:  opts.GasPrice,
+		GasFeeCap: opts.GasFeeCap,
+		GasTipCap: opts.GasTipCap,
+	}, nil
+}
+
+// confirmTransaction shows the summary of a transaction and asks whether to
+// sign and send it
+func confirmTransaction(ts *internal.TransactionSummary) bool {
+	if output.IsTable() {
+		fmt.Printf("transaction summary:\n")
+	}
+	printRecord(ts)
+	ok, _ := ui.InputYesNo("sign and send transaction? (%s): ", false)
+	return ok
+}
+
+func executeTransactMethod(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string, opts *bind.TransactOpts, args []interface{}) (*types.Transaction, error) {
+	if abi.Methods[name].IsConstant() {
+		return nil, errConstant
+	}
+	// the gas limit is estimated by prepareTransaction, which decodes the
+	// revert data that bind drops
+	bc := bind.NewBoundContract(*addr, *abi, cl, cl, cl)
+	return bc.Transact(opts, name, args...)
+}
+
+func inputFilterOpts() (*bind.FilterOpts, error) {
 	opts := &bind.FilterOpts{}
 	startBlock, ok := ui.InputIntWithDefault("start block (%d): ", 0)
 	if !ok {
-		fmt.Printf("aborted\n")
-		return
+		return nil, errAborted
 	}
 	opts.Start = uint64(startBlock)
 	if lastBlock, ok := ui.InputIntWithDefault("end block (last, %d): ", -1); !ok {
-		fmt.Printf("aborted\n")
-		return
+		return nil, errAborted
 	} else if lastBlock >= 0 {
 		lb := uint64(lastBlock)
 		opts.End = &lb
 	}
+	return opts, nil
+}
+
+func listEvents(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string, opts *bind.FilterOpts, filters [][]interface{}, eventFn func(types.Log, map[string]interface{})) error {
 	bc := bind.NewBoundContract(*addr, *abi, cl, cl, cl)
 	logs, sub, err := bc.FilterLogs(opts, name, filters...)
 	if err != nil {
-		fmt.Printf("error listing logs: %s\n", err)
-		return
+		return err
 	}
-	defer close(logs)
 	defer sub.Unsubscribe()
-	for {
-		if err := <-sub.Err(); err != nil {
-			fmt.Printf("error listing logs: %s\n", err)
-			return
+	unpack := func(l types.Log) error {
+		eventData := make(map[string]interface{}, 8)
+		if err := bc.UnpackLogIntoMap(eventData, name, l); err != nil {
+			return err
 		}
+		eventFn(l, eventData)
+		return nil
+	}
+	for {
 		select {
 		case l := <-logs:
-			eventData := make(map[string]interface{}, 8)
-			if err := bc.UnpackLogIntoMap(eventData, name, l); err != nil {
-				fmt.Printf("error listing logs: %s\n", err)
-				return
+			if err := unpack(l); err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			if err != nil {
+				return err
+			}
+			// all logs were delivered, drain the buffer
+			for {
+				select {
+				case l := <-logs:
+					if err := unpack(l); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
 			}
-			fmt.Print(formatEvent(abi.Events[name].Inputs, eventData, l.BlockNumber))
-		default:
-			return
 		}
 	}
-
 }
 
-func formatEvent(inputs abi.Arguments, eventData map[string]interface{}, blockNumber uint64) string {
-	var values []string
-	for _, i := range inputs {
-		b, err := json.Marshal(eventData[i.Name])
-		if err != nil {
-			fmt.Printf("can't marshal value: %s\n", err)
-			continue
-		}
-		values = append(values, fmt.Sprintf("%s=%s", i.Name, string(b)))
+func newEventRecord(event abi.Event, l types.Log, eventData map[string]interface{}) *internal.EventRecord {
+	return &internal.EventRecord{
+		Event:       event.Name,
+		Args:        event.Inputs,
+		Values:      eventData,
+		BlockNumber: l.BlockNumber,
+		TxHash:      l.TxHash,
+		LogIndex:    l.Index,
 	}
-	return fmt.Sprintf("  block %d: %s\n", blockNumber, strings.Join(values, " "))
 }
 
 func watchEvents(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name string) {
-	filters, err := inputFilters(abi.Events[name].Inputs)
+	filters, err := inputFilters(name, abi.Events[name].Inputs)
 	if err != nil {
 		fmt.Printf("error parsing filter fields: %s\n", err)
 		return
@@ -366,7 +533,7 @@ func watchEvents(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, name
 				fmt.Printf("error watching logs: %s\n", err)
 				return
 			}
-			fmt.Print(formatEvent(abi.Events[name].Inputs, eventData, l.BlockNumber))
+			printRecord(newEventRecord(abi.Events[name], l, eventData))
 		}
 	}
 }
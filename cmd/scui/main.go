@@ -1,52 +1,143 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/c-bata/go-prompt"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/heliorosa/scui/internal"
 	"github.com/heliorosa/scui/signer"
 	"github.com/heliorosa/scui/ui"
 )
 
-var txSigner *signer.Signer
+var (
+	txSigner signer.Signer
+	output   *internal.Printer
+	// contracts of the interactive session
+	contracts *workspace
+	// global flags
+	outputFormat = "table"
+	receiptArgs  = internal.NewReceiptArgs()
+)
+
+func globalFlags(fs *flag.FlagSet) *flag.FlagSet {
+	fs.StringVar(&outputFormat, "output", outputFormat, internal.OutputFormatUsage)
+	fs.BoolVar(&internal.HumanUnits, "units", internal.HumanUnits, internal.HumanUnitsUsage)
+	receiptArgs.AddFlags(fs)
+	return fs
+}
+
+// history key of the menu commands
+const menuHistory = "menu"
+
+// loadHistory loads the prompt history of the contract at addr in the chain
+// of cl
+func loadHistory(cl *ethclient.Client, addr common.Address) error {
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		return internal.WrapError("can't get chain id", err)
+	}
+	dir, err := internal.ConfigDir("history", chainID.String())
+	if err != nil {
+		return err
+	}
+	return ui.LoadHistory(filepath.Join(dir, strings.ToLower(addr.Hex())+".json"))
+}
+
+// loadAddressBook loads the address book of the chain of cl
+func loadAddressBook(cl *ethclient.Client) error {
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		return internal.WrapError("can't get chain id", err)
+	}
+	return internal.LoadAddressBook(chainID)
+}
+
+// contractAddress parses the address argument, a hex address, an @name from
+// the address book or "-" for the address of the chain in the artifact
+func contractAddress(cl *ethclient.Client, s string, a *internal.Artifact) (common.Address, error) {
+	if s != "-" {
+		return internal.ResolveAddress(s)
+	}
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		return common.Address{}, internal.WrapError("can't get chain id", err)
+	}
+	r, ok := a.Address(chainID)
+	if !ok {
+		return common.Address{}, fmt.Errorf("artifact has no address for chain %s", chainID)
+	}
+	return r, nil
+}
 
 func main() {
-	if len(os.Args) != 4 {
-		internal.ErrorExit(-1, "missing arguments: usage: %s <client_url> <address> <abi_file>\n", os.Args[0])
+	fs := globalFlags(flag.NewFlagSet(os.Args[0], flag.ExitOnError))
+	fs.Usage = showCommandsUsage
+	fs.Parse(os.Args[1:])
+	args := fs.Args()
+	if len(args) > 0 {
+		if cmd, ok := txCommands[args[0]]; ok {
+			of, err := internal.ParseOutputFormat(outputFormat)
+			if err != nil {
+				internal.ErrorExit(-1, "%s\n", err)
+			}
+			output = internal.NewPrinter(os.Stdout, of)
+			cmd(args[1:])
+			output.Close()
+			return
+		}
 	}
+	if len(args) < 3 {
+		internal.ErrorExit(-1, "missing arguments: usage: %s [flags] <client_url> <address|@name|-> <abi_or_artifact_file> [command [arguments]]\n", os.Args[0])
+	}
+	of, err := internal.ParseOutputFormat(outputFormat)
+	if err != nil {
+		internal.ErrorExit(-1, "%s\n", err)
+	}
+	output = internal.NewPrinter(os.Stdout, of)
 	// dial client
-	cl, err := ethclient.Dial(os.Args[1])
+	cl, err := ethclient.Dial(args[0])
 	if err != nil {
 		internal.ErrorExit(-2, "can't dial client: %s\n", err)
 	}
 	defer cl.Close()
-	// parse contract address
-	contractAddr := common.HexToAddress(os.Args[2])
-	// read and parse abi file
-	contractABI, err := internal.ReadABI(os.Args[3])
+	// resolve @name addresses with the address book of the chain
+	if err = loadAddressBook(cl); err != nil {
+		fmt.Fprintf(os.Stderr, "address book disabled: %s\n", err)
+	}
+	// read the abi, bare or from an artifact
+	artifact, err := internal.ReadArtifact(args[2])
 	if err != nil {
 		internal.ErrorExit(-3, "can't read abi: %s\n", err)
 	}
-	// setup constant and transaction method calls
-	constantNode, transactNode := methodsMenus(contractABI.Methods)
-	// setup events
-	eventsNode, listEventNode, watchEventNode := eventsMenu(contractABI.Events)
-	// setup root node
-	rootNode := ui.NewRootNode([]*ui.MenuCompleter{
-		constantNode,
-		transactNode,
-		eventsNode,
-		newSignerMenu(),
-	})
-	curNode := rootNode
+	contractABI := artifact.ABI
+	// parse contract address
+	contractAddr, err := contractAddress(cl, args[1], artifact)
+	if err != nil {
+		internal.ErrorExit(-11, "invalid contract address: %s\n", err)
+	}
+	// run a single command and exit
+	if len(args) > 3 {
+		runCommand(cl, &contractAddr, contractABI, args[3:])
+		output.Close()
+		return
+	}
+	output.Indent = "  "
+	contracts = newWorkspace(cl)
+	contracts.add(&contract{name: artifact.Name, addr: contractAddr, abi: contractABI, abiFile: args[2]})
+	contracts.use(artifact.Name)
+	curNode := contracts.rootNode
 	fmt.Printf("\nWelcome to scui.\nType \"help\" for a list of available commands or press <TAB> for auto-complete\n\n")
 	for {
-		inp := prompt.Input(curNode.Prompt(">"), curNode.Completer)
+		inp := prompt.Input(contracts.prompt(curNode), curNode.Completer, ui.HistoryOption(menuHistory))
+		ui.AddHistory(menuHistory, inp)
 	Outer:
 		switch inp {
 		case "exit":
@@ -54,7 +145,7 @@ func main() {
 		case "help":
 			ui.ShowHelp(curNode)
 		case "..":
-			if curNode != rootNode {
+			if curNode != contracts.rootNode {
 				curNode = curNode.Parent
 			}
 		case "":
@@ -71,9 +162,16 @@ func main() {
 				break Outer
 			}
 			if sub.Sub == nil {
+				contractAddr, contractABI := contracts.active.addr, contracts.active.abi
 				switch sub.Parent {
-				case constantNode:
-					r, err := executeConstantMethod(cl, &contractAddr, contractABI, sub.Suggestion.Text)
+				case contracts.constantNode:
+					fmt.Printf("constant call arguments:\n")
+					args, err := inputArguments(sub.Suggestion.Text, contractABI.Methods[sub.Suggestion.Text].Inputs)
+					if err != nil {
+						fmt.Printf("can't parse arguments: %s\n", err)
+						break
+					}
+					r, err := executeConstantMethod(cl, &contractAddr, contractABI, sub.Suggestion.Text, args)
 					if err != nil {
 						fmt.Printf(
 							"can't execute contant method \"%s\": %s\n",
@@ -82,26 +180,44 @@ func main() {
 						)
 						break
 					}
-					fmt.Printf("returned:\n")
-					for nj, j := range r {
-						b, err := json.Marshal(j)
-						if err != nil {
-							fmt.Printf("can't marshal result: %s\n", err)
-							break
-						}
-						me := contractABI.Methods[sub.Suggestion.Text].Outputs[nj]
-						pref := me.Type.String()
-						if me.Name != "" {
-							pref += " " + me.Name
-						}
-						fmt.Printf("  (%s) %s\n", pref, string(b))
-					}
-				case transactNode:
-					if txSigner.Kind() == signer.None {
+					if output.IsTable() {
+						fmt.Printf("returned:\n")
+					}
+					printRecord(&internal.CallResult{
+						Method:  sub.Suggestion.Text,
+						Args:    contractABI.Methods[sub.Suggestion.Text].Outputs,
+						Results: r,
+					})
+				case contracts.transactNode:
+					if txSigner == nil {
 						fmt.Printf("signer not set\n")
 						break
 					}
-					tx, err := executeTransactMethod(cl, &contractAddr, contractABI, sub.Suggestion.Text)
+					fmt.Printf("transaction arguments:\n")
+					args, err := inputArguments(sub.Suggestion.Text, contractABI.Methods[sub.Suggestion.Text].Inputs)
+					if err != nil {
+						fmt.Printf("can't parse arguments: %s\n", err)
+						break
+					}
+					opts, err := inputTransactOpts(cl, contractABI, sub.Suggestion.Text)
+					if err != nil {
+						fmt.Printf("can't setup transaction: %s\n", err)
+						break
+					}
+					if !inputSimulation(cl, &contractAddr, contractABI, sub.Suggestion.Text, opts, args) {
+						fmt.Printf("aborted\n")
+						break
+					}
+					ts, err := prepareTransaction(cl, &contractAddr, contractABI, sub.Suggestion.Text, opts, args)
+					if err != nil {
+						fmt.Printf("can't prepare transaction: %s\n", err)
+						break
+					}
+					if !confirmTransaction(ts) {
+						fmt.Printf("aborted\n")
+						break
+					}
+					tx, err := executeTransactMethod(cl, &contractAddr, contractABI, sub.Suggestion.Text, opts, args)
 					if err != nil {
 						fmt.Printf(
 							"can't send transaction to method %s: %s\n",
@@ -110,10 +226,35 @@ func main() {
 						)
 						break
 					}
-					fmt.Printf("transaction sent: %s\n", tx.Hash().Hex())
-				case listEventNode:
-					listEvents(cl, &contractAddr, contractABI, sub.Suggestion.Text)
-				case watchEventNode:
+					printRecord(&internal.TransactionRecord{Method: sub.Suggestion.Text, TxHash: tx.Hash()})
+					if wait, ok := ui.InputYesNo("wait for receipt? (%s): ", receiptArgs.Wait); !ok || !wait {
+						break
+					}
+					rr, err := waitReceipt(cl, &contractAddr, contractABI, tx)
+					if err != nil {
+						fmt.Printf("%s\n", err)
+						break
+					}
+					printRecord(rr)
+				case contracts.listEventNode:
+					name := sub.Suggestion.Text
+					filters, err := inputFilters(name, contractABI.Events[name].Inputs)
+					if err != nil {
+						fmt.Printf("error parsing filter fields: %s\n", err)
+						break
+					}
+					opts, err := inputFilterOpts()
+					if err != nil {
+						fmt.Printf("%s\n", err)
+						break
+					}
+					err = listEvents(cl, &contractAddr, contractABI, name, opts, filters, func(l types.Log, eventData map[string]interface{}) {
+						printRecord(newEventRecord(contractABI.Events[name], l, eventData))
+					})
+					if err != nil {
+						fmt.Printf("error listing logs: %s\n", err)
+					}
+				case contracts.watchEventNode:
 					watchEvents(cl, &contractAddr, contractABI, sub.Suggestion.Text)
 				default:
 					cmd := sub.Name()
@@ -129,5 +270,7 @@ func main() {
 				break Outer
 			}
 		}
+		// every command prints its own json array
+		output.Flush()
 	}
 }
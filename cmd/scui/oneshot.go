@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/heliorosa/scui/internal"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+type commandFunc func(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, args []string)
+
+var oneShotCommands = map[string]commandFunc{
+	"call": cmdCall,
+	"send": cmdSend,
+	"logs": cmdLogs,
+}
+
+func showCommandsUsage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [flags] <client_url> <address|@name|-> <abi_or_artifact_file> [command [arguments]]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s [flags] sign [--yes] [--chain-id id] [--out file] <signer_flag(s)> <unsigned_tx_file>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s [flags] broadcast <client_url> <signed_tx_file | raw_tx | ->\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "sign signs a transaction written by --unsigned-out without connecting to a node,\nbroadcast sends a signed transaction. Transactions are hex RLP or JSON, the unsigned\nones have the sender and the chain id, and can only be signed by the sender\n\n")
+	fmt.Fprintf(os.Stderr, "the abi can be read from hardhat, truffle and foundry artifacts and from solc\n--combined-json output (file.json:Name picks a contract). \"-\" uses the address\nof the chain in a truffle artifact\n\n")
+	fmt.Fprintf(os.Stderr, "flags:\n")
+	globalFlags(flag.NewFlagSet("", flag.ExitOnError)).PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\ncommands:\n")
+	fmt.Fprintf(os.Stderr, "  call <method> [arguments]\n\tcall a constant method\n")
+	fmt.Fprintf(os.Stderr, "  send [--dry-run] [--yes] [--unsigned-out file | --sign-only] <signer_flag(s)> [gas_flag(s)] <method> [arguments]\n\tsend a transaction to a method, --unsigned-out takes --from instead of a signer\n")
+	fmt.Fprintf(os.Stderr, "  logs <event> [--from block] [--to block] [indexed_values]\n\tlist events, \"*\" matches any indexed value\n\n")
+	internal.NewSignatureArgsParser().NewFlagSet("send", flag.ExitOnError).Usage()
+}
+
+// runCommand executes a single command and exits with a non-zero code on failure
+func runCommand(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, args []string) {
+	cmd, ok := oneShotCommands[args[0]]
+	if !ok {
+		showCommandsUsage()
+		internal.ErrorExit(-4, "\nunknown command: %s\n", args[0])
+	}
+	cmd(cl, addr, abi, args[1:])
+}
+
+func lookupMethod(abi *abi.ABI, args []string) (*abi.Method, []string) {
+	if len(args) == 0 {
+		showCommandsUsage()
+		internal.ErrorExit(-5, "\nmissing method name\n")
+	}
+	m, ok := abi.Methods[args[0]]
+	if !ok {
+		internal.ErrorExit(-5, "method not found: %s\n", args[0])
+	}
+	return &m, args[1:]
+}
+
+func cmdCall(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, args []string) {
+	method, args := lookupMethod(abi, args)
+	callArgs, err := internal.ParseArguments(method.Inputs, args)
+	if err != nil {
+		internal.ErrorExit(-6, "invalid arguments: %s\n", err)
+	}
+	r, err := executeConstantMethod(cl, addr, abi, method.Name, callArgs)
+	if err != nil {
+		internal.ErrorExit(-7, "can't execute constant method \"%s\": %s\n", method.Name, err)
+	}
+	printRecord(&internal.CallResult{Method: method.Name, Args: method.Outputs, Results: r})
+}
+
+func cmdSend(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, args []string) {
+	sigArgsParser := internal.NewSignatureArgsParser()
+	fs := sigArgsParser.NewFlagSet("send", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "simulate the transaction without sending it")
+	yes := fs.Bool("yes", false, "send without showing the summary and asking for confirmation")
+	unsignedOut := fs.String("unsigned-out", "", "write the unsigned transaction to a file instead of sending it, as JSON if the file is .json or as hex RLP")
+	signOnly := fs.Bool("sign-only", false, "print the signed transaction instead of sending it")
+	if err := fs.Parse(args); err != nil {
+		internal.ErrorExit(-5, "invalid arguments: %s\n", err)
+	}
+	method, args := lookupMethod(abi, fs.Args())
+	txArgs, err := internal.ParseArguments(method.Inputs, args)
+	if err != nil {
+		internal.ErrorExit(-6, "invalid arguments: %s\n", err)
+	}
+	sigArgs, err := sigArgsParser.SignatureArgs()
+	if err != nil {
+		internal.ErrorExit(-5, "can't parse arguments: %s\n", err)
+	}
+	if sigArgs.Unsigned() && *unsignedOut == "" && !*dryRun {
+		internal.ErrorExit(-5, "-from can only be used with -unsigned-out or -dry-run\n")
+	}
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		internal.ErrorExit(-7, "can't get chain id: %s\n", err)
+	}
+	opts := sigArgs.TransactOpts(chainID)
+	if err = internal.FillFees(cl, opts, sigArgs.Legacy()); err != nil {
+		internal.ErrorExit(-7, "can't set transaction fees: %s\n", err)
+	}
+	if *dryRun {
+		sr, err := simulateTransaction(cl, addr, abi, method.Name, opts, txArgs)
+		if err != nil {
+			internal.ErrorExit(-7, "can't simulate transaction: %s\n", err)
+		}
+		printRecord(sr)
+		if sr.Reverted {
+			internal.ErrorExit(-9, "transaction reverted\n")
+		}
+		return
+	}
+	ts, err := prepareTransaction(cl, addr, abi, method.Name, opts, txArgs)
+	if err != nil {
+		internal.ErrorExit(-7, "can't prepare transaction: %s\n", err)
+	}
+	if *unsignedOut != "" {
+		// build the transaction without signing it
+		opts.Signer = internal.UnsignedSigner(chainID)
+		opts.NoSend = true
+		printRecord(ts)
+	} else {
+		if !*yes && !terminal.IsTerminal(int(syscall.Stdin)) {
+			internal.ErrorExit(-10, "can't ask for confirmation without a terminal, use --yes\n")
+		}
+		if !*yes && !confirmTransaction(ts) {
+			internal.ErrorExit(-10, "aborted\n")
+		}
+		opts.NoSend = *signOnly
+	}
+	tx, err := executeTransactMethod(cl, addr, abi, method.Name, opts, txArgs)
+	if err != nil {
+		internal.ErrorExit(-7, "can't send transaction to method %s: %s\n", method.Name, err)
+	}
+	if opts.NoSend {
+		if *unsignedOut != "" {
+			if err = internal.WriteTransaction(*unsignedOut, tx, opts.From, chainID); err != nil {
+				internal.ErrorExit(-7, "%s\n", err)
+			}
+		}
+		printRecord(internal.NewRawTransactionRecord(tx, opts.From, chainID, *unsignedOut))
+		return
+	}
+	printRecord(&internal.TransactionRecord{Method: method.Name, TxHash: tx.Hash()})
+	if !receiptArgs.Wait {
+		return
+	}
+	rr, err := waitReceipt(cl, addr, abi, tx)
+	if err != nil {
+		internal.ErrorExit(-8, "%s\n", err)
+	}
+	printRecord(rr)
+	if rr.Status != types.ReceiptStatusSuccessful {
+		internal.ErrorExit(-9, "transaction failed\n")
+	}
+}
+
+func cmdLogs(cl *ethclient.Client, addr *common.Address, abi *abi.ABI, args []string) {
+	if len(args) == 0 {
+		showCommandsUsage()
+		internal.ErrorExit(-5, "\nmissing event name\n")
+	}
+	name := args[0]
+	event, ok := abi.Events[name]
+	if !ok {
+		internal.ErrorExit(-5, "event not found: %s\n", name)
+	}
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	from := fs.Uint64("from", 0, "start block")
+	to := fs.Int64("to", -1, "end block (-1 for the last block)")
+	if err := fs.Parse(args[1:]); err != nil {
+		internal.ErrorExit(-5, "invalid arguments: %s\n", err)
+	}
+	opts := &bind.FilterOpts{Start: *from}
+	if *to >= 0 {
+		end := uint64(*to)
+		opts.End = &end
+	}
+	// one value per indexed field, "*" matches any value
+	values := fs.Args()
+	filters := make([][]interface{}, 0, len(values))
+	for _, i := range event.Inputs {
+		if !i.Indexed || len(values) == 0 {
+			continue
+		}
+		v := values[0]
+		values = values[1:]
+		if v == "*" {
+			filters = append(filters, nil)
+			continue
+		}
+		fv, err := internal.ParseValue(i.Type, v)
+		if err != nil {
+			internal.ErrorExit(-6, "can't parse filter value for %s (%s): %s\n", i.Name, i.Type.String(), err)
+		}
+		filters = append(filters, []interface{}{fv})
+	}
+	if len(values) != 0 {
+		internal.ErrorExit(-6, "too many filter values\n")
+	}
+	err := listEvents(cl, addr, abi, name, opts, filters, func(l types.Log, eventData map[string]interface{}) {
+		printRecord(newEventRecord(event, l, eventData))
+	})
+	if err != nil {
+		internal.ErrorExit(-7, "error listing logs: %s\n", err)
+	}
+}
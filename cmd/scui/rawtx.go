@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/heliorosa/scui/internal"
+	"github.com/heliorosa/scui/signer"
+	"github.com/heliorosa/scui/ui"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// commands that don't take a contract
+var txCommands = map[string]func(args []string){
+	"sign":      cmdSign,
+	"broadcast": cmdBroadcast,
+}
+
+// cmdSign signs a transaction built with send --unsigned-out. It doesn't
+// connect to a node, to be used in an air-gapped machine
+func cmdSign(args []string) {
+	sigArgsParser := internal.NewSignatureArgsParser()
+	fs := sigArgsParser.NewFlagSet("sign", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "sign without showing the summary and asking for confirmation")
+	chainIDFlag := fs.String("chain-id", "", "chain id, checked against the one recorded with the transaction or needed by the legacy transactions without it")
+	out := fs.String("out", "", "write the signed transaction to a file, as JSON if the file is .json or as hex RLP")
+	if err := fs.Parse(args); err != nil {
+		internal.ErrorExit(-5, "invalid arguments: %s\n", err)
+	}
+	if fs.NArg() != 1 {
+		internal.ErrorExit(-5, "expecting the unsigned transaction file\n")
+	}
+	tx, from, chainID, err := internal.ReadTransaction(fs.Arg(0))
+	if err != nil {
+		internal.ErrorExit(-6, "%s\n", err)
+	}
+	if internal.IsSigned(tx) {
+		internal.ErrorExit(-6, "the transaction is already signed\n")
+	}
+	if *chainIDFlag != "" {
+		c, ok := internal.ParseBigInt(*chainIDFlag)
+		if !ok {
+			internal.ErrorExit(-5, "invalid chain id: %s\n", *chainIDFlag)
+		}
+		if chainID != nil && chainID.Cmp(c) != 0 {
+			internal.ErrorExit(-6, "the transaction was built for the chain %s, not %s\n", chainID, c)
+		}
+		chainID = c
+	}
+	if chainID, err = internal.TransactionChainID(tx, chainID); err != nil {
+		internal.ErrorExit(-6, "%s, set it with --chain-id\n", err)
+	}
+	// resolve @name addresses with the address book of the chain
+	if err = internal.LoadAddressBook(chainID); err != nil {
+		fmt.Fprintf(os.Stderr, "address book disabled: %s\n", err)
+	}
+	sigArgs, err := sigArgsParser.SignatureArgs()
+	if err != nil {
+		internal.ErrorExit(-5, "can't parse arguments: %s\n", err)
+	}
+	if sigArgs.Unsigned() {
+		internal.ErrorExit(-5, "expecting a signer\n")
+	}
+	if tx.Type() != types.LegacyTxType && signer.LegacyOnly(sigArgs.Signer()) {
+		internal.ErrorExit(-6, "%s can only sign legacy transactions, build it with --legacy\n", sigArgs.Signer().Describe())
+	}
+	// the nonce and the gas were computed for the sender
+	if from != nil && *from != sigArgs.From() {
+		internal.ErrorExit(-6, "the transaction was built for %s, it can't be signed by %s\n", from.Hex(), sigArgs.From().Hex())
+	}
+	if !*yes {
+		if !terminal.IsTerminal(int(syscall.Stdin)) {
+			internal.ErrorExit(-10, "can't ask for confirmation without a terminal, use --yes\n")
+		}
+		if output.IsTable() {
+			fmt.Printf("transaction summary:\n")
+		}
+		printRecord(internal.NewRawTransactionSummary(tx, sigArgs.From(), chainID))
+		if ok, _ := ui.InputYesNo("sign transaction? (%s): ", false); !ok {
+			internal.ErrorExit(-10, "aborted\n")
+		}
+	}
+	if tx, err = sigArgs.Signer().SignTx(tx, chainID); err != nil {
+		internal.ErrorExit(-7, "can't sign transaction: %s\n", err)
+	}
+	if *out != "" {
+		if err = internal.WriteTransaction(*out, tx, sigArgs.From(), chainID); err != nil {
+			internal.ErrorExit(-7, "%s\n", err)
+		}
+	}
+	printRecord(internal.NewRawTransactionRecord(tx, sigArgs.From(), chainID, *out))
+}
+
+// cmdBroadcast sends a signed transaction
+func cmdBroadcast(args []string) {
+	if len(args) != 2 {
+		internal.ErrorExit(-1, "expecting the client url and the signed transaction\n")
+	}
+	tx, _, _, err := internal.ReadTransaction(args[1])
+	if err != nil {
+		internal.ErrorExit(-6, "%s\n", err)
+	}
+	if !internal.IsSigned(tx) {
+		internal.ErrorExit(-6, "the transaction isn't signed, sign it with the sign command\n")
+	}
+	cl, err := ethclient.Dial(args[0])
+	if err != nil {
+		internal.ErrorExit(-2, "can't dial client: %s\n", err)
+	}
+	defer cl.Close()
+	chainID, err := cl.ChainID(context.Background())
+	if err != nil {
+		internal.ErrorExit(-7, "can't get chain id: %s\n", err)
+	}
+	if _, err = internal.TransactionChainID(tx, chainID); err != nil {
+		internal.ErrorExit(-6, "%s\n", err)
+	}
+	if _, err = types.Sender(types.LatestSignerForChainID(chainID), tx); err != nil {
+		internal.ErrorExit(-6, "invalid signature: %s\n", err)
+	}
+	if err = cl.SendTransaction(context.Background(), tx); err != nil {
+		internal.ErrorExit(-7, "can't send transaction: %s\n", err)
+	}
+	printRecord(&internal.TransactionRecord{TxHash: tx.Hash()})
+	if !receiptArgs.Wait {
+		return
+	}
+	// without abi the events aren't decoded
+	addr := common.Address{}
+	if tx.To() != nil {
+		addr = *tx.To()
+	}
+	rr, err := waitReceipt(cl, &addr, &abi.ABI{}, tx)
+	if err != nil {
+		internal.ErrorExit(-8, "%s\n", err)
+	}
+	printRecord(rr)
+	if rr.Status != types.ReceiptStatusSuccessful {
+		internal.ErrorExit(-9, "transaction failed\n")
+	}
+}
@@ -1,18 +1,16 @@
 package main
 
 import (
-	"crypto/ecdsa"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"path/filepath"
-	"reflect"
 	"sort"
 
 	"github.com/c-bata/go-prompt"
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/heliorosa/scui/internal"
+	"github.com/heliorosa/scui/signer"
 	"github.com/heliorosa/scui/ui"
 )
 
@@ -21,46 +19,161 @@ func newSignerMenu() *ui.MenuCompleter {
 		Text:        "signer",
 		Description: "configure signer",
 	}}
-	sigKey := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
-		Text:        "key",
-		Description: "sign with a key",
-	}}
-	sigLedger := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
-		Text:        "ledger",
-		Description: "sign with ledger",
-	}}
+	for _, b := range signer.NewBackends() {
+		r.Sub = append(r.Sub, &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
+			Text:        b.Name(),
+			Description: b.Description(),
+		}})
+	}
 	sigShow := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
 		Text:        "show",
 		Description: "show signed configuration",
 	}}
-	r.Sub = append([]*ui.MenuCompleter{sigKey, sigLedger, sigShow}, ui.TailCommands...)
+	r.Sub = append(append(r.Sub, sigShow), ui.TailCommands...)
 	return r
 }
 
-func inputKeyFile() (*ecdsa.PrivateKey, error) {
+func newContractsMenu() *ui.MenuCompleter {
+	r := &ui.MenuCompleter{Suggestion: &prompt.Suggest{
+		Text:        "contracts",
+		Description: "manage the contracts of the session",
+	}}
+	add := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
+		Text:        "add",
+		Description: "add a contract",
+	}}
+	remove := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
+		Text:        "remove",
+		Description: "remove a contract",
+	}}
+	list := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
+		Text:        "list",
+		Description: "list the contracts",
+	}}
+	use := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
+		Text:        "use",
+		Description: "switch to another contract",
+	}}
+	r.Sub = append([]*ui.MenuCompleter{add, remove, list, use}, ui.TailCommands...)
+	return r
+}
+
+func newAddressBookMenu() *ui.MenuCompleter {
+	r := &ui.MenuCompleter{Suggestion: &prompt.Suggest{
+		Text:        "addressbook",
+		Description: "manage the named addresses of the chain",
+	}}
+	add := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
+		Text:        "add",
+		Description: "name an address",
+	}}
+	remove := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
+		Text:        "remove",
+		Description: "remove a name",
+	}}
+	list := &ui.MenuCompleter{Parent: r, Suggestion: &prompt.Suggest{
+		Text:        "list",
+		Description: "list the named addresses",
+	}}
+	r.Sub = append([]*ui.MenuCompleter{add, remove, list}, ui.TailCommands...)
+	return r
+}
+
+// inputAddress reads a hex address or an @name from the address book. def
+// is returned for empty input if it isn't nil
+func inputAddress(name string, def *common.Address) (common.Address, error) {
+	pr := name + ": "
+	if def != nil {
+		pr = fmt.Sprintf("%s (%s): ", name, def.Hex())
+	}
+	for {
+		s := ui.InputTextHistory(pr, "address", ui.AddressCompleter)
+		switch {
+		case s == "..":
+			return common.Address{}, errAborted
+		case s == "" && def != nil:
+			return *def, nil
+		}
+		a, err := internal.ResolveAddress(s)
+		if err == nil {
+			return a, nil
+		}
+		fmt.Printf("%s\n", err)
+	}
+}
+
+// uiPrompter asks the signer backends' questions in the console
+type uiPrompter struct{}
+
+func (uiPrompter) Text(msg string) string { return ui.InputText(msg) }
+
+func (uiPrompter) Password(msg string) (string, error) {
+	fmt.Print(msg)
+	return internal.PromptPassword()
+}
+
+func (uiPrompter) Filename(msg string) (string, error) {
 	p, err := filepath.Abs(".")
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	keyFile, err := ui.InputFilename("key file: ", p, true)
+	return ui.InputFilename(msg, p, true)
+}
+
+func (uiPrompter) Directory(msg string) (string, error) {
+	p, err := filepath.Abs(".")
+	if err != nil {
+		return "", err
+	}
+	// the path is checked by the backend, InputFilename only takes files
+	return ui.InputFilename(msg, p, false)
+}
+
+func (uiPrompter) YesNo(msg string, def bool) (bool, bool) {
+	return ui.InputYesNo(msg+" (%s): ", def)
+}
+
+func (uiPrompter) Choice(msg string, def string, choices []string) (string, bool) {
+	return ui.InputMultiChoiceString(msg+" (%s): ", def, choices, func(c []prompt.Suggest) {
+		fmt.Printf("choose one of the options\n")
+	})
+}
+
+// inputContract reads the name, address and abi or artifact file of a
+// contract
+func inputContract() (*contract, error) {
+	p, err := filepath.Abs(".")
 	if err != nil {
 		return nil, err
 	}
-	b, err := ioutil.ReadFile(keyFile)
+	// don't check if the file exists, it can be followed by :name
+	abiFile, err := ui.InputFilename("abi or artifact file: ", p, false)
 	if err != nil {
 		return nil, err
 	}
-	encrypted, ok := ui.InputYesNo("encrypted? (%s): ", false)
-	if !ok {
-		return nil, errors.New("bad response")
+	artifact, err := internal.ReadArtifact(abiFile)
+	if err != nil {
+		return nil, err
 	}
-	var password string
-	if encrypted {
-		if password, err = ui.InputPassword(); err != nil {
-			return nil, err
+	// offer the address in the artifact as default
+	var def *common.Address
+	if chainID, err := contracts.cl.ChainID(context.Background()); err == nil {
+		if a, ok := artifact.Address(chainID); ok {
+			def = &a
 		}
 	}
-	return internal.ParseKey(b, encrypted, password)
+	addr, err := inputAddress("address", def)
+	if err != nil {
+		return nil, err
+	}
+	name := ui.InputText(fmt.Sprintf("name (%s): ", artifact.Name))
+	switch name {
+	case "":
+		name = artifact.Name
+	case "..":
+		return nil, errAborted
+	}
+	return &contract{name: name, addr: addr, abi: artifact.ABI, abiFile: abiFile}, nil
 }
 
 func methodsMenus(methods map[string]abi.Method) (*ui.MenuCompleter, *ui.MenuCompleter) {
@@ -127,68 +240,151 @@ func eventsMenu(events map[string]abi.Event) (*ui.MenuCompleter, *ui.MenuComplet
 	return eventsNode, listNode, watchNode
 }
 
-func inputArguments(args abi.Arguments) ([]interface{}, error) {
+// inputArguments reads the arguments of method. the values typed for each
+// argument are kept in its own history
+func inputArguments(method string, args abi.Arguments) ([]interface{}, error) {
 	r := make([]interface{}, 0, len(args))
 	for _, i := range args {
-		for {
-			val := ui.InputText(i.Name + " (" + i.Type.String() + "): ")
-			if val == "" {
+		v, err := inputValue(method, i.Name, i.Type)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, v)
+	}
+	return r, nil
+}
+
+// inputValue reads a value of type t. arrays, slices and tuples can be
+// entered as a JSON literal or element by element
+func inputValue(method string, name string, t abi.Type) (interface{}, error) {
+	key := method + "/" + name
+	completer := valueCompleter(t)
+	for {
+		if !internal.IsComposite(t) {
+			val := ui.InputTextHistory(name+" ("+t.String()+"): ", key, completer)
+			switch val {
+			case "":
 				fmt.Printf("....\n")
 				continue
+			case "..":
+				return nil, errAborted
+			}
+			v, err := internal.ParseValue(t, val)
+			if err != nil {
+				fmt.Printf("can't parse value: %s\n", err)
+				continue
 			}
-			v, err := unmarshalValue(val, i.Type.GetType())
+			return v, nil
+		}
+		val := ui.InputTextHistory(name+" ("+internal.FormatType(t)+", JSON or empty to enter each element): ", key, completer)
+		switch val {
+		case "":
+			elems, err := inputElements(method, name, t)
 			if err != nil {
 				return nil, err
 			}
-			r = append(r, v)
-			break
+			return internal.ConvertValue(t, elems)
+		case "..":
+			return nil, errAborted
+		}
+		v, err := internal.ParseValue(t, val)
+		if err != nil {
+			fmt.Printf("can't parse value: %s\n", err)
+			continue
 		}
+		return v, nil
 	}
-	return r, nil
 }
 
-func unmarshalValue(val string, t reflect.Type) (interface{}, error) {
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
+// valueCompleter completes the names in the address book for types that
+// hold addresses
+func valueCompleter(t abi.Type) prompt.Completer {
+	if hasAddress(t) {
+		return ui.AddressCompleter
 	}
-	v := reflect.New(t).Interface()
-	b, err := json.Marshal(v)
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+func hasAddress(t abi.Type) bool {
+	switch t.T {
+	case abi.AddressTy:
+		return true
+	case abi.ArrayTy, abi.SliceTy:
+		return hasAddress(*t.Elem)
+	case abi.TupleTy:
+		for _, i := range t.TupleElems {
+			if hasAddress(*i) {
+				return true
+			}
+		}
 	}
-	if len(b) > 1 && b[0] == '"' {
-		val = "\"" + val + "\""
+	return false
+}
+
+func inputElements(method string, name string, t abi.Type) ([]interface{}, error) {
+	var (
+		names []string
+		types []abi.Type
+	)
+	switch t.T {
+	case abi.TupleTy:
+		for i, e := range t.TupleElems {
+			names = append(names, name+"."+t.TupleRawNames[i])
+			types = append(types, *e)
+		}
+	case abi.ArrayTy, abi.SliceTy:
+		size := t.Size
+		if t.T == abi.SliceTy {
+			var ok bool
+			if size, ok = ui.InputIntWithDefault(name+" length (%d): ", 0); !ok {
+				return nil, errAborted
+			}
+		}
+		for i := 0; i < size; i++ {
+			names = append(names, fmt.Sprintf("%s[%d]", name, i))
+			types = append(types, *t.Elem)
+		}
 	}
-	if err = json.Unmarshal([]byte(val), v); err != nil {
-		return nil, err
+	r := make([]interface{}, 0, len(types))
+	for i, et := range types {
+		v, err := inputValue(method, names[i], et)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, v)
 	}
-	return v, nil
+	return r, nil
 }
 
-func inputFilters(inputs abi.Arguments) ([][]interface{}, error) {
+func inputFilters(event string, inputs abi.Arguments) ([][]interface{}, error) {
 	r := make([][]interface{}, 0, 4)
 	for _, i := range inputs {
 		if !i.Indexed {
 			continue
 		}
 		pr := fmt.Sprintf("field %s (%s) is indexed. filter? (%%s): ", i.Name, i.Type.String())
-		if filterField, ok := ui.InputYesNo(pr, false); !ok {
+		filterField, ok := ui.InputYesNo(pr, false)
+		if !ok {
 			return nil, errAborted
-		} else if filterField {
-			for {
-				v := ui.InputText("field value (none): ")
-				if v == "" {
-					r = append(r, nil)
-					break
-				}
-				fv, err := unmarshalValue(v, i.Type.GetType())
-				if err != nil {
-					fmt.Printf("can't parse value: %s\n", err)
-					continue
-				}
-				r = append(r, []interface{}{fv})
+		}
+		// the filters are positional, skipped fields match any value
+		if !filterField {
+			r = append(r, nil)
+			continue
+		}
+		for {
+			v := ui.InputTextHistory("field value (none): ", event+"/"+i.Name, valueCompleter(i.Type))
+			if v == "" {
+				r = append(r, nil)
 				break
 			}
+			fv, err := internal.ParseValue(i.Type, v)
+			if err != nil {
+				fmt.Printf("can't parse value: %s\n", err)
+				continue
+			}
+			r = append(r, []interface{}{fv})
+			break
 		}
 	}
 	return r, nil
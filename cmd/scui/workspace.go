@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/heliorosa/scui/ui"
+)
+
+type contract struct {
+	name    string
+	addr    common.Address
+	abi     *abi.ABI
+	abiFile string
+}
+
+// workspace holds the contracts of the session and the menus of the active
+// one
+type workspace struct {
+	cl        *ethclient.Client
+	contracts map[string]*contract
+	active    *contract
+	rootNode  *ui.MenuCompleter
+	// menus of the active contract
+	constantNode   *ui.MenuCompleter
+	transactNode   *ui.MenuCompleter
+	eventsNode     *ui.MenuCompleter
+	listEventNode  *ui.MenuCompleter
+	watchEventNode *ui.MenuCompleter
+}
+
+func newWorkspace(cl *ethclient.Client) *workspace {
+	return &workspace{cl: cl, contracts: make(map[string]*contract, 4)}
+}
+
+func (ws *workspace) add(c *contract) error {
+	if _, ok := ws.contracts[c.name]; ok {
+		return fmt.Errorf("contract already exists: %s", c.name)
+	}
+	ws.contracts[c.name] = c
+	return nil
+}
+
+func (ws *workspace) remove(name string) error {
+	if _, ok := ws.contracts[name]; !ok {
+		return fmt.Errorf("contract not found: %s", name)
+	}
+	if ws.active != nil && ws.active.name == name {
+		return fmt.Errorf("can't remove the active contract")
+	}
+	delete(ws.contracts, name)
+	return nil
+}
+
+func (ws *workspace) names() []string {
+	r := make([]string, 0, len(ws.contracts))
+	for i := range ws.contracts {
+		r = append(r, i)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// use makes name the active contract and rebuilds the menus of the root node
+func (ws *workspace) use(name string) error {
+	c, ok := ws.contracts[name]
+	if !ok {
+		return fmt.Errorf("contract not found: %s", name)
+	}
+	ws.active = c
+	ws.constantNode, ws.transactNode = methodsMenus(c.abi.Methods)
+	ws.eventsNode, ws.listEventNode, ws.watchEventNode = eventsMenu(c.abi.Events)
+	entries := []*ui.MenuCompleter{ws.constantNode, ws.transactNode, ws.eventsNode}
+	if ws.rootNode == nil {
+		ws.rootNode = ui.NewRootNode(append(entries, newSignerMenu(), newContractsMenu(), newAddressBookMenu()))
+	} else {
+		for i, e := range entries {
+			e.Parent = ws.rootNode
+			ws.rootNode.Sub[i] = e
+		}
+	}
+	// keep the history of this contract
+	if err := loadHistory(ws.cl, c.addr); err != nil {
+		fmt.Printf("history disabled: %s\n", err)
+	}
+	return nil
+}
+
+// prompt returns the prompt of node, prefixed by the active contract
+func (ws *workspace) prompt(node *ui.MenuCompleter) string {
+	return "(" + ws.active.name + ") " + node.Prompt(">")
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AddressBook maps names to the addresses of a chain
+type AddressBook struct {
+	path    string
+	entries map[string]common.Address
+}
+
+// Addresses is the address book used to resolve @name addresses, nil when
+// there's none
+var Addresses *AddressBook
+
+// LoadAddressBook reads the address book of the chain with chainID from the
+// config directory and makes it the one used to resolve addresses
+func LoadAddressBook(chainID *big.Int) error {
+	dir, err := ConfigDir("addressbook")
+	if err != nil {
+		return err
+	}
+	ab := &AddressBook{
+		path:    filepath.Join(dir, chainID.String()+".json"),
+		entries: make(map[string]common.Address, 16),
+	}
+	b, err := ioutil.ReadFile(ab.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return WrapError("can't read address book", err)
+	}
+	if err == nil {
+		if err = json.Unmarshal(b, &ab.entries); err != nil {
+			return WrapError("can't parse address book", err)
+		}
+	}
+	Addresses = ab
+	return nil
+}
+
+// Lookup returns the address named name
+func (ab *AddressBook) Lookup(name string) (common.Address, bool) {
+	if ab == nil {
+		return common.Address{}, false
+	}
+	a, ok := ab.entries[name]
+	return a, ok
+}
+
+// Names returns the sorted names in the address book
+func (ab *AddressBook) Names() []string {
+	if ab == nil {
+		return nil
+	}
+	r := make([]string, 0, len(ab.entries))
+	for i := range ab.entries {
+		r = append(r, i)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// Set names addr and saves the address book
+func (ab *AddressBook) Set(name string, addr common.Address) error {
+	if name == "" || strings.ContainsAny(name, " \t@,[]{}\":") {
+		return fmt.Errorf("invalid name: %q", name)
+	}
+	ab.entries[name] = addr
+	return ab.save()
+}
+
+// Remove removes name and saves the address book
+func (ab *AddressBook) Remove(name string) error {
+	if _, ok := ab.entries[name]; !ok {
+		return fmt.Errorf("name not found: %s", name)
+	}
+	delete(ab.entries, name)
+	return ab.save()
+}
+
+func (ab *AddressBook) save() error {
+	b, err := json.MarshalIndent(ab.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(ab.path, b, 0600); err != nil {
+		return WrapError("can't save address book", err)
+	}
+	return nil
+}
+
+// ResolveAddress parses a hex address or the name of an address book entry
+// prefixed by @
+func ResolveAddress(s string) (common.Address, error) {
+	if name := strings.TrimPrefix(s, "@"); name != s {
+		a, ok := Addresses.Lookup(name)
+		if !ok {
+			return common.Address{}, fmt.Errorf("address not found in the address book: %s", name)
+		}
+		return a, nil
+	}
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("invalid address: %s", s)
+	}
+	return common.HexToAddress(s), nil
+}
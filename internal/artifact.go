@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Artifact is a compiled contract. The bytecodes are hex strings that can
+// have placeholders for libraries
+type Artifact struct {
+	Name             string
+	ABI              *abi.ABI
+	Bytecode         string
+	DeployedBytecode string
+	// addresses by chain id, from truffle
+	Networks map[string]common.Address
+	// library placeholders in Bytecode and DeployedBytecode, by source file
+	// and library name
+	LinkReferences         map[string]map[string][]LinkReference
+	DeployedLinkReferences map[string]map[string][]LinkReference
+	// immutable variables in DeployedBytecode, by AST id
+	ImmutableReferences map[string][]LinkReference
+	// source files of the artifact and, with solc --combined-json, of the
+	// other contracts, to qualify the library names of the placeholders
+	Sources []string
+}
+
+// rawArtifact has the fields of the hardhat, truffle and foundry artifacts
+// and of the solc combined json output
+type rawArtifact struct {
+	ContractName     string                                `json:"contractName"`
+	ABI              json.RawMessage                       `json:"abi"`
+	Bytecode         json.RawMessage                       `json:"bytecode"`
+	DeployedBytecode json.RawMessage                       `json:"deployedBytecode"`
+	LinkReferences   map[string]map[string][]LinkReference `json:"linkReferences"`
+	// hardhat
+	SourceName             string                                `json:"sourceName"`
+	DeployedLinkReferences map[string]map[string][]LinkReference `json:"deployedLinkReferences"`
+	// truffle
+	ImmutableReferences map[string][]LinkReference `json:"immutableReferences"`
+	Networks            map[string]struct {
+		Address common.Address `json:"address"`
+	} `json:"networks"`
+	Contracts map[string]struct {
+		ABI        json.RawMessage `json:"abi"`
+		Bin        string          `json:"bin"`
+		BinRuntime string          `json:"bin-runtime"`
+	} `json:"contracts"`
+}
+
+var errNoABI = errors.New("no abi found")
+
+// ReadArtifact reads a bare ABI, a hardhat, truffle or foundry artifact or
+// the output of solc --combined-json. Files with several contracts take the
+// name of the contract after a colon, e.g. "combined.json:Token"
+func ReadArtifact(fn string) (*Artifact, error) {
+	var name string
+	if _, err := os.Stat(fn); err != nil {
+		if i := strings.LastIndex(fn, ":"); i > 0 {
+			fn, name = fn[:i], fn[i+1:]
+		}
+	}
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, WrapError("can't read file", err)
+	}
+	b = bytes.TrimSpace(b)
+	// bare abi
+	if len(b) > 0 && b[0] == '[' {
+		a, err := parseABI(b)
+		if err != nil {
+			return nil, err
+		}
+		return &Artifact{Name: fileContractName(fn), ABI: a}, nil
+	}
+	var raw rawArtifact
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return nil, WrapError("can't parse artifact", err)
+	}
+	if raw.Contracts != nil {
+		return combinedArtifact(&raw, name)
+	}
+	if len(raw.ABI) == 0 {
+		return nil, errNoABI
+	}
+	r := &Artifact{Name: raw.ContractName}
+	if r.Name == "" {
+		r.Name = fileContractName(fn)
+	}
+	if name != "" && name != r.Name {
+		return nil, fmt.Errorf("contract not found: %s", name)
+	}
+	if r.ABI, err = parseABI(raw.ABI); err != nil {
+		return nil, err
+	}
+	bc, err := bytecodeObject(raw.Bytecode)
+	if err != nil {
+		return nil, err
+	}
+	dbc, err := bytecodeObject(raw.DeployedBytecode)
+	if err != nil {
+		return nil, err
+	}
+	r.Bytecode, r.LinkReferences = bc.Object, bc.LinkReferences
+	r.DeployedBytecode, r.DeployedLinkReferences = dbc.Object, dbc.LinkReferences
+	r.ImmutableReferences = dbc.ImmutableReferences
+	if raw.LinkReferences != nil {
+		r.LinkReferences = raw.LinkReferences
+	}
+	if raw.DeployedLinkReferences != nil {
+		r.DeployedLinkReferences = raw.DeployedLinkReferences
+	}
+	if raw.ImmutableReferences != nil {
+		r.ImmutableReferences = raw.ImmutableReferences
+	}
+	if raw.SourceName != "" {
+		r.Sources = []string{raw.SourceName}
+	}
+	if len(raw.Networks) > 0 {
+		r.Networks = make(map[string]common.Address, len(raw.Networks))
+		for id, n := range raw.Networks {
+			r.Networks[id] = n.Address
+		}
+	}
+	return r, nil
+}
+
+// combinedArtifact picks the contract called name, or the only contract, from
+// the output of solc --combined-json. The contracts are keyed by
+// "source:name" and can be picked by either
+func combinedArtifact(raw *rawArtifact, name string) (*Artifact, error) {
+	keys := make([]string, 0, len(raw.Contracts))
+	sources := make(map[string]struct{}, len(raw.Contracts))
+	for i := range raw.Contracts {
+		if j := strings.LastIndex(i, ":"); j > 0 {
+			sources[i[:j]] = struct{}{}
+		}
+		if name == "" || i == name || strings.HasSuffix(i, ":"+name) {
+			keys = append(keys, i)
+		}
+	}
+	sort.Strings(keys)
+	switch {
+	case len(keys) == 0 && name != "":
+		return nil, fmt.Errorf("contract not found: %s", name)
+	case len(keys) == 0:
+		return nil, errors.New("no contracts found")
+	case len(keys) > 1:
+		return nil, fmt.Errorf("more than one contract, pick one with file:name: %s", strings.Join(keys, ", "))
+	}
+	c := raw.Contracts[keys[0]]
+	r := &Artifact{
+		Name:             keys[0][strings.LastIndex(keys[0], ":")+1:],
+		Bytecode:         c.Bin,
+		DeployedBytecode: c.BinRuntime,
+		Sources:          make([]string, 0, len(sources)),
+	}
+	for i := range sources {
+		r.Sources = append(r.Sources, i)
+	}
+	sort.Strings(r.Sources)
+	// older versions of solc have the abi as a string
+	abiJSON := []byte(c.ABI)
+	var s string
+	if err := json.Unmarshal(c.ABI, &s); err == nil {
+		abiJSON = []byte(s)
+	}
+	if len(abiJSON) == 0 {
+		return nil, errNoABI
+	}
+	var err error
+	if r.ABI, err = parseABI(abiJSON); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func parseABI(b []byte) (*abi.ABI, error) {
+	r, err := abi.JSON(bytes.NewReader(b))
+	if err != nil {
+		return nil, WrapError("can't parse abi", err)
+	}
+	return &r, nil
+}
+
+// bytecodeJSON is a bytecode object of foundry and solc --standard-json
+type bytecodeJSON struct {
+	Object              string                                `json:"object"`
+	LinkReferences      map[string]map[string][]LinkReference `json:"linkReferences"`
+	ImmutableReferences map[string][]LinkReference            `json:"immutableReferences"`
+}
+
+// bytecodeObject returns the bytecode of hardhat and truffle, a string, or
+// the one of foundry, an object with the bytecode in the "object" field and
+// its link and immutable references
+func bytecodeObject(b json.RawMessage) (*bytecodeJSON, error) {
+	r := &bytecodeJSON{}
+	if len(b) == 0 || string(b) == "null" {
+		return r, nil
+	}
+	if err := json.Unmarshal(b, &r.Object); err == nil {
+		return r, nil
+	}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, WrapError("can't parse bytecode", err)
+	}
+	return r, nil
+}
+
+// fileContractName returns the name of the contract in fn, the file name
+// without extension
+func fileContractName(fn string) string {
+	return strings.TrimSuffix(filepath.Base(fn), filepath.Ext(fn))
+}
+
+// Code returns the decoded bytecode
+func (a *Artifact) Code() ([]byte, error) {
+	return DecodeBytecode(a.Bytecode)
+}
+
+// Address returns the address of the contract in the chain with chainID
+func (a *Artifact) Address(chainID *big.Int) (common.Address, bool) {
+	r, ok := a.Networks[chainID.String()]
+	return r, ok
+}
+
+// DecodeBytecode decodes hex bytecode, with or without 0x prefix
+func DecodeBytecode(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0x" {
+		return nil, errors.New("no bytecode")
+	}
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		s = "0x" + s
+	}
+	if strings.Contains(s, "__") {
+		return nil, errors.New("bytecode has unlinked libraries")
+	}
+	r, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, WrapError("can't parse bytecode", err)
+	}
+	return r, nil
+}
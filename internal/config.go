@@ -0,0 +1,20 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ConfigDir returns the directory where scui keeps its files, creating it if
+// it doesn't exist
+func ConfigDir(elem ...string) (string, error) {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return "", WrapError("can't find config directory", err)
+	}
+	d = filepath.Join(append([]string{d, "scui"}, elem...)...)
+	if err = os.MkdirAll(d, 0700); err != nil {
+		return "", WrapError("can't create config directory", err)
+	}
+	return d, nil
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DefaultCreate2Factory is the deterministic deployment proxy, deployed at
+// the same address in most chains
+const DefaultCreate2Factory = "0x4e59b44847b379578588920ca78fbf26c0b4956c"
+
+// ParseSalt parses a hex salt of up to 32 bytes, padded on the left
+func ParseSalt(s string) (common.Hash, error) {
+	if s == "" {
+		return common.Hash{}, nil
+	}
+	if !strings.HasPrefix(s, "0x") {
+		s = "0x" + s
+	}
+	if len(s)%2 != 0 {
+		s = "0x0" + s[2:]
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return common.Hash{}, WrapError("invalid salt", err)
+	}
+	if len(b) > common.HashLength {
+		return common.Hash{}, fmt.Errorf("salt is longer than %d bytes", common.HashLength)
+	}
+	return common.BytesToHash(b), nil
+}
+
+// Create2Address returns the address of the contract deployed by factory
+// with salt and initCode
+func Create2Address(factory common.Address, salt common.Hash, initCode []byte) common.Address {
+	return crypto.CreateAddress2(factory, salt, crypto.Keccak256(initCode))
+}
+
+// Create2Data returns the calldata of the factory, the salt followed by the
+// init code
+func Create2Data(salt common.Hash, initCode []byte) []byte {
+	return append(salt.Bytes(), initCode...)
+}
+
+// DeployCreate2 sends the transaction that deploys initCode with salt through
+// factory
+func DeployCreate2(cl *ethclient.Client, opts *bind.TransactOpts, factory common.Address, salt common.Hash, initCode []byte) (*types.Transaction, error) {
+	code, err := cl.CodeAt(context.Background(), factory, nil)
+	if err != nil {
+		return nil, WrapError("can't get factory code", err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("no CREATE2 factory at %s", factory.Hex())
+	}
+	bc := bind.NewBoundContract(factory, abi.ABI{}, cl, cl, cl)
+	return bc.RawTransact(opts, Create2Data(salt, initCode))
+}
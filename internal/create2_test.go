@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestCreate2Address(t *testing.T) {
+	// the examples of eip-1014
+	for i, tt := range []struct {
+		factory  string
+		salt     string
+		initCode string
+		want     string
+	}{
+		{"0x0000000000000000000000000000000000000000", "0x00", "0x00", "0x4D1A2e2bB4F88F0250f26Ffff098B0b30B26BF38"},
+		{"0xdeadbeef00000000000000000000000000000000", "0x00", "0x00", "0xB928f69Bb1D91Cd65274e3c79d8986362984fDA3"},
+		{"0xdeadbeef00000000000000000000000000000000", "0x000000000000000000000000feed000000000000000000000000000000000000", "0x00", "0xD04116cDd17beBE565EB2422F2497E06cC1C9833"},
+		{"0x0000000000000000000000000000000000000000", "0x00", "0xdeadbeef", "0x70f2b2914A2a4b783FaEFb75f459A580616Fcb5e"},
+		{"0x00000000000000000000000000000000deadbeef", "0xcafebabe", "0xdeadbeef", "0x60f3f640a8508fC6a86d45DF051962668E1e8AC7"},
+		{"0x00000000000000000000000000000000deadbeef", "0xcafebabe", "0x" + strings.Repeat("deadbeef", 11), "0x1d8bfDC5D46DC4f61D6b6115972536eBE6A8854C"},
+		{"0x0000000000000000000000000000000000000000", "0x00", "0x", "0xE33C0C7F7df4809055C3ebA6c09CFe4BaF1BD9e0"},
+	} {
+		salt, err := ParseSalt(tt.salt)
+		if err != nil {
+			t.Fatalf("example %d: %s", i, err)
+		}
+		got := Create2Address(common.HexToAddress(tt.factory), salt, hexutil.MustDecode(tt.initCode))
+		if got != common.HexToAddress(tt.want) {
+			t.Errorf("example %d: got %s, want %s", i, got.Hex(), tt.want)
+		}
+	}
+}
+
+func TestCreate2Data(t *testing.T) {
+	salt := common.HexToHash("0xcafebabe")
+	got := Create2Data(salt, []byte{0xde, 0xad, 0xbe, 0xef})
+	want := append(common.LeftPadBytes([]byte{0xca, 0xfe, 0xba, 0xbe}, 32), 0xde, 0xad, 0xbe, 0xef)
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+	if salt != common.HexToHash("0xcafebabe") {
+		t.Error("the salt was modified")
+	}
+}
+
+func TestParseSalt(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want string
+	}{
+		{"", "0x00"},
+		{"0x1", "0x01"},
+		{"abc", "0x0abc"},
+		{"0x" + strings.Repeat("ff", 32), "0x" + strings.Repeat("ff", 32)},
+		{"0x" + strings.Repeat("ff", 33), ""},
+		{"0xzz", ""},
+	} {
+		got, err := ParseSalt(tt.s)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%q: got %s, want an error", tt.s, got.Hex())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tt.s, err)
+		} else if got != common.HexToHash(tt.want) {
+			t.Errorf("%q: got %s, want %s", tt.s, got.Hex(), tt.want)
+		}
+	}
+}
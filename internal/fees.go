@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// blocks of fee history used to suggest the priority fee
+	feeHistoryBlocks = 10
+	// percentile of the priority fees paid in each block
+	feeHistoryPercentile = 50
+)
+
+var errNoBaseFee = errors.New("chain doesn't support dynamic fee transactions")
+
+// DynamicFees reports whether the chain supports dynamic fee transactions
+func DynamicFees(cl *ethclient.Client) (bool, error) {
+	head, err := cl.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return false, WrapError("can't get the last block", err)
+	}
+	return head.BaseFee != nil, nil
+}
+
+// SuggestFees suggests the max priority fee and the max fee per gas of a
+// dynamic fee transaction. The priority fee is the median of the ones paid in
+// the last blocks and the max fee allows the base fee to double. It returns
+// nil caps for chains without London
+func SuggestFees(cl *ethclient.Client) (tipCap *big.Int, feeCap *big.Int, err error) {
+	ctx := context.Background()
+	head, err := cl.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, WrapError("can't get the last block", err)
+	}
+	if head.BaseFee == nil {
+		return nil, nil, nil
+	}
+	fh, err := cl.FeeHistory(ctx, feeHistoryBlocks, nil, []float64{feeHistoryPercentile})
+	if err != nil {
+		return nil, nil, WrapError("can't get fee history", err)
+	}
+	rewards := make([]*big.Int, 0, len(fh.Reward))
+	for _, i := range fh.Reward {
+		if len(i) > 0 && i[0] != nil {
+			rewards = append(rewards, i[0])
+		}
+	}
+	if len(rewards) > 0 {
+		sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+		tipCap = new(big.Int).Set(rewards[len(rewards)/2])
+	}
+	if tipCap == nil || tipCap.Sign() == 0 {
+		// empty blocks don't tell much, ask the node
+		if tipCap, err = cl.SuggestGasTipCap(ctx); err != nil {
+			return nil, nil, WrapError("can't suggest priority fee", err)
+		}
+	}
+	// the history includes the base fee of the next block
+	baseFee := head.BaseFee
+	if sz := len(fh.BaseFee); sz > 0 {
+		baseFee = fh.BaseFee[sz-1]
+	}
+	feeCap = new(big.Int).Add(tipCap, new(big.Int).Mul(baseFee, big.NewInt(2)))
+	return tipCap, feeCap, nil
+}
+
+// FillFees sets the fees of opts that weren't set. Dynamic fees are used
+// unless legacy is set or the chain doesn't support them
+func FillFees(cl *ethclient.Client, opts *bind.TransactOpts, legacy bool) error {
+	if opts.GasPrice != nil {
+		return nil
+	}
+	dynamic := opts.GasFeeCap != nil || opts.GasTipCap != nil
+	if legacy {
+		if dynamic {
+			return errors.New("legacy transactions can't have dynamic fees")
+		}
+		gp, err := cl.SuggestGasPrice(context.Background())
+		if err != nil {
+			return WrapError("can't suggest gas price", err)
+		}
+		opts.GasPrice = gp
+		return nil
+	}
+	tipCap, feeCap, err := SuggestFees(cl)
+	if err != nil {
+		return err
+	}
+	if feeCap == nil {
+		if dynamic {
+			return errNoBaseFee
+		}
+		// no London, fall back to legacy
+		return FillFees(cl, opts, true)
+	}
+	if opts.GasTipCap == nil {
+		opts.GasTipCap = tipCap
+	}
+	if opts.GasFeeCap == nil {
+		// keep the room for the base fee with the chosen priority fee
+		opts.GasFeeCap = new(big.Int).Add(new(big.Int).Sub(feeCap, tipCap), opts.GasTipCap)
+	}
+	if opts.GasFeeCap.Cmp(opts.GasTipCap) < 0 {
+		return errors.New("max fee per gas is lower than the max priority fee per gas")
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// LinkReference is the position in bytes of a library address in bytecode
+type LinkReference struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// length of a placeholder in hex bytecode
+const placeholderLen = 2 * common.AddressLength
+
+// Libraries maps library names to addresses. The names can be qualified by
+// their source file, as in "contracts/Math.sol:Math"
+type Libraries map[string]common.Address
+
+// LibrariesFlag collects name=address flags. The addresses are resolved
+// later, when the address book is loaded
+type LibrariesFlag []string
+
+func (lf *LibrariesFlag) String() string { return strings.Join(*lf, ",") }
+
+func (lf *LibrariesFlag) Set(s string) error {
+	if i := strings.Index(s, "="); i <= 0 {
+		return fmt.Errorf("expecting name=address: %s", s)
+	}
+	*lf = append(*lf, s)
+	return nil
+}
+
+// Libraries resolves the addresses of the flags, which can be @names from the
+// address book
+func (lf LibrariesFlag) Libraries() (Libraries, error) {
+	r := make(Libraries, len(lf))
+	for _, i := range lf {
+		parts := strings.SplitN(i, "=", 2)
+		addr, err := ResolveAddress(parts[1])
+		if err != nil {
+			return nil, WrapError("invalid address for library "+parts[0], err)
+		}
+		r[parts[0]] = addr
+	}
+	return r, nil
+}
+
+// lookup returns the address of the library qualified by source, which can be
+// empty if it isn't known. Without source the name can only match one
+// qualified library
+func (l Libraries) lookup(source string, name string) (common.Address, bool, error) {
+	if a, ok := l[source+":"+name]; ok && source != "" {
+		return a, true, nil
+	}
+	if a, ok := l[name]; ok {
+		return a, true, nil
+	}
+	var matches []string
+	for i := range l {
+		if strings.HasSuffix(i, ":"+name) {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return common.Address{}, false, nil
+	case 1:
+		return l[matches[0]], true, nil
+	}
+	sort.Strings(matches)
+	return common.Address{}, false, fmt.Errorf("ambiguous library %s, qualify it with the source file: %s", name, strings.Join(matches, ", "))
+}
+
+// splitLibraryName splits a library name qualified by its source file
+func splitLibraryName(qname string) (string, string) {
+	if i := strings.LastIndex(qname, ":"); i >= 0 {
+		return qname[:i], qname[i+1:]
+	}
+	return "", qname
+}
+
+// PlaceholderHint returns a hint for the unlinked libraries that are
+// placeholder hashes, which only match the qualified library name. It's empty
+// if there are none
+func PlaceholderHint(unlinked []string) string {
+	for _, i := range unlinked {
+		if strings.HasPrefix(i, "__$") {
+			return "__$...$__ placeholders are hashes of the library name qualified by its source file, as in file.sol:Name"
+		}
+	}
+	return ""
+}
+
+// placeholderHash returns the hash in the placeholders of solc >= 0.5 for the
+// library with the qualified name
+func placeholderHash(name string) string {
+	return hex.EncodeToString(crypto.Keccak256([]byte(name)))[:34]
+}
+
+// Link replaces the placeholders of libs in the bytecode and the deployed
+// bytecode, using the link references if there are any. It returns the
+// libraries left unlinked
+func (a *Artifact) Link(libs Libraries) ([]string, error) {
+	unlinked := make(map[string]struct{}, 4)
+	code, err := linkCode(a.Bytecode, a.LinkReferences, libs, a.Sources, unlinked)
+	if err != nil {
+		return nil, err
+	}
+	deployed, err := linkCode(a.DeployedBytecode, a.DeployedLinkReferences, libs, a.Sources, unlinked)
+	if err != nil {
+		return nil, WrapError("deployed bytecode", err)
+	}
+	r := make([]string, 0, len(unlinked))
+	for i := range unlinked {
+		r = append(r, i)
+	}
+	sort.Strings(r)
+	if len(r) == 0 {
+		a.Bytecode, a.DeployedBytecode = code, deployed
+	}
+	return r, nil
+}
+
+// linkCode returns the hex bytecode in code with the placeholders of libs
+// replaced, adding the libraries left unlinked to unlinked. The library names
+// without source file are qualified with each of sources
+func linkCode(code string, refs map[string]map[string][]LinkReference, libs Libraries, sources []string, unlinked map[string]struct{}) (string, error) {
+	prefix := ""
+	code = strings.TrimSpace(code)
+	if strings.HasPrefix(code, "0x") {
+		prefix, code = "0x", code[2:]
+	}
+	b := []byte(code)
+	// qualified names of the placeholder hashes
+	hashes := make(map[string]string, len(libs))
+	for i := range libs {
+		hashes[placeholderHash(i)] = i
+		if !strings.Contains(i, ":") {
+			for _, src := range sources {
+				hashes[placeholderHash(src+":"+i)] = src + ":" + i
+			}
+		}
+	}
+	for source, refs := range refs {
+		for name, lr := range refs {
+			qname := source + ":" + name
+			hashes[placeholderHash(qname)] = qname
+			addr, ok, err := libs.lookup(source, name)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				unlinked[qname] = struct{}{}
+				continue
+			}
+			for _, i := range lr {
+				start := 2 * i.Start
+				if i.Start < 0 || start+placeholderLen > len(b) {
+					return "", fmt.Errorf("link reference of %s at byte %d is past the end of the bytecode", qname, i.Start)
+				}
+				copy(b[start:], hex.EncodeToString(addr[:]))
+			}
+		}
+	}
+	// placeholders without link references: __$hash$__ from solc >= 0.5, and
+	// __name__ padded with _ from older versions
+	for i := strings.Index(string(b), "__"); i >= 0 && i+placeholderLen <= len(b); i = strings.Index(string(b), "__") {
+		ph := string(b[i : i+placeholderLen])
+		var (
+			addr common.Address
+			ok   bool
+			name string
+			err  error
+		)
+		if strings.HasPrefix(ph, "__$") && strings.HasSuffix(ph, "$__") {
+			name = ph
+			if qname, found := hashes[ph[3:37]]; found {
+				name = qname
+				src, lib := splitLibraryName(qname)
+				addr, ok, err = libs.lookup(src, lib)
+			}
+		} else {
+			name = strings.Trim(ph, "_")
+			src, lib := splitLibraryName(name)
+			addr, ok, err = libs.lookup(src, lib)
+		}
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			unlinked[name] = struct{}{}
+			// keep looking after the placeholder
+			copy(b[i:], strings.Repeat("0", placeholderLen))
+			continue
+		}
+		copy(b[i:], hex.EncodeToString(addr[:]))
+	}
+	return prefix + string(b), nil
+}
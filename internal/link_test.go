@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestLinkCode(t *testing.T) {
+	const (
+		mathAddr  = "1111111111111111111111111111111111111111"
+		otherAddr = "2222222222222222222222222222222222222222"
+		qname     = "contracts/Math.sol:Math"
+	)
+	hashPh := "__$" + placeholderHash(qname) + "$__"
+	namePh := "__Math" + strings.Repeat("_", placeholderLen-6)
+	zeros := strings.Repeat("0", placeholderLen)
+	refs := map[string]map[string][]LinkReference{
+		"contracts/Math.sol": {"Math": {{Start: 2, Length: common.AddressLength}}},
+	}
+	for _, tt := range []struct {
+		name     string
+		code     string
+		refs     map[string]map[string][]LinkReference
+		libs     Libraries
+		sources  []string
+		want     string
+		unlinked []string
+		err      string
+	}{
+		{
+			name: "hash placeholder",
+			code: "0x6000" + hashPh + "6000",
+			libs: Libraries{qname: common.HexToAddress(mathAddr)},
+			want: "0x6000" + mathAddr + "6000",
+		},
+		{
+			name:    "hash placeholder of a source",
+			code:    "6000" + hashPh + "6000" + hashPh,
+			libs:    Libraries{"Math": common.HexToAddress(mathAddr)},
+			sources: []string{"contracts/Other.sol", "contracts/Math.sol"},
+			want:    "6000" + mathAddr + "6000" + mathAddr,
+		},
+		{
+			name:     "unknown hash placeholder",
+			code:     "0x6000" + hashPh,
+			libs:     Libraries{"contracts/Other.sol:Math": common.HexToAddress(otherAddr)},
+			want:     "0x6000" + zeros,
+			unlinked: []string{hashPh},
+		},
+		{
+			name: "name placeholder",
+			code: "0x6000" + namePh + "6000",
+			libs: Libraries{"Math": common.HexToAddress(mathAddr)},
+			want: "0x6000" + mathAddr + "6000",
+		},
+		{
+			name: "link references",
+			code: "0x6000" + zeros + "6000",
+			refs: refs,
+			libs: Libraries{"Math": common.HexToAddress(mathAddr)},
+			want: "0x6000" + mathAddr + "6000",
+		},
+		{
+			name: "qualified link references",
+			code: "0x6000" + hashPh + "6000",
+			refs: refs,
+			libs: Libraries{
+				qname:                      common.HexToAddress(mathAddr),
+				"contracts/Other.sol:Math": common.HexToAddress(otherAddr),
+			},
+			want: "0x6000" + mathAddr + "6000",
+		},
+		{
+			name:     "unlinked link references",
+			code:     "0x6000" + zeros,
+			refs:     refs,
+			libs:     Libraries{"Other": common.HexToAddress(otherAddr)},
+			want:     "0x6000" + zeros,
+			unlinked: []string{qname},
+		},
+		{
+			name: "ambiguous library",
+			code: "0x6000" + namePh,
+			libs: Libraries{
+				"a.sol:Math": common.HexToAddress(mathAddr),
+				"b.sol:Math": common.HexToAddress(otherAddr),
+			},
+			err: "ambiguous library Math, qualify it with the source file: a.sol:Math, b.sol:Math",
+		},
+		{
+			name: "reference past the end",
+			code: "0x6000" + zeros[:placeholderLen-2],
+			refs: refs,
+			libs: Libraries{"Math": common.HexToAddress(mathAddr)},
+			err:  "link reference of contracts/Math.sol:Math at byte 2 is past the end of the bytecode",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			unlinked := make(map[string]struct{})
+			got, err := linkCode(tt.code, tt.refs, tt.libs, tt.sources, unlinked)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("got error %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got code %s, want %s", got, tt.want)
+			}
+			var names []string
+			for i := range unlinked {
+				names = append(names, i)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.unlinked) {
+				t.Errorf("got unlinked %v, want %v", names, tt.unlinked)
+			}
+		})
+	}
+}
@@ -0,0 +1,568 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type OutputFormat int
+
+const (
+	TableOutput OutputFormat = iota
+	JSONOutput
+	NDJSONOutput
+)
+
+var outputFormats = map[string]OutputFormat{
+	"table":  TableOutput,
+	"json":   JSONOutput,
+	"ndjson": NDJSONOutput,
+}
+
+// OutputFormatUsage describes the accepted output formats for flag help
+const OutputFormatUsage = "output format: table, json (an array with the results) or ndjson (a result per line)"
+
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	f, ok := outputFormats[s]
+	if !ok {
+		return TableOutput, fmt.Errorf("invalid output format: %s", s)
+	}
+	return f, nil
+}
+
+// Record is a result that can be printed as a table line or as a JSON object
+type Record interface {
+	Table() string
+}
+
+// Printer prints records. With json they are the elements of an array,
+// which is ended by Flush or Close
+type Printer struct {
+	w      io.Writer
+	format OutputFormat
+	// Indent is prepended to every table line
+	Indent string
+	// records in the current json array
+	n int
+}
+
+// printers are flushed by ErrorExit, to end their json arrays
+var printers []*Printer
+
+func NewPrinter(w io.Writer, format OutputFormat) *Printer {
+	p := &Printer{w: w, format: format}
+	printers = append(printers, p)
+	return p
+}
+
+func (p *Printer) IsTable() bool { return p.format == TableOutput }
+
+func (p *Printer) Print(r Record) error {
+	var (
+		b   []byte
+		err error
+	)
+	switch p.format {
+	case JSONOutput:
+		if b, err = json.MarshalIndent(r, "  ", "  "); err != nil {
+			break
+		}
+		sep := ",\n  "
+		if p.n == 0 {
+			sep = "[\n  "
+		}
+		p.n++
+		_, err = fmt.Fprintf(p.w, "%s%s", sep, b)
+		return err
+	case NDJSONOutput:
+		b, err = json.Marshal(r)
+	default:
+		lines := strings.Split(strings.TrimSuffix(r.Table(), "\n"), "\n")
+		for i := range lines {
+			lines[i] = p.Indent + lines[i]
+		}
+		b = []byte(strings.Join(lines, "\n"))
+	}
+	if err != nil {
+		return WrapError("can't marshal result", err)
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", b)
+	return err
+}
+
+// Flush ends the json array if any record was printed, the next record starts
+// a new one
+func (p *Printer) Flush() error {
+	if p.format != JSONOutput || p.n == 0 {
+		return nil
+	}
+	p.n = 0
+	_, err := fmt.Fprintf(p.w, "\n]\n")
+	return err
+}
+
+// Close ends the output, with json an empty array if no record was printed
+func (p *Printer) Close() error {
+	if p.format == JSONOutput && p.n == 0 {
+		_, err := fmt.Fprintf(p.w, "[]\n")
+		return err
+	}
+	return p.Flush()
+}
+
+type CallResult struct {
+	Method  string
+	Args    abi.Arguments
+	Results []interface{}
+}
+
+func (cr *CallResult) Table() string {
+	var sb strings.Builder
+	for i, v := range cr.Results {
+		pref := cr.Args[i].Type.String()
+		if cr.Args[i].Name != "" {
+			pref += " " + cr.Args[i].Name
+		}
+		fmt.Fprintf(&sb, "(%s) %s\n", pref, tableValue(cr.Args[i].Type, v, textValue(cr.Args[i].Type, v)))
+	}
+	return sb.String()
+}
+
+func (cr *CallResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Method  string                 `json:"method"`
+		Outputs map[string]interface{} `json:"outputs"`
+	}{cr.Method, namedValues(cr.Args, cr.Results)})
+}
+
+type EventRecord struct {
+	Event       string
+	Args        abi.Arguments
+	Values      map[string]interface{}
+	BlockNumber uint64
+	TxHash      common.Hash
+	LogIndex    uint
+}
+
+func (er *EventRecord) Table() string {
+	values := make([]string, 0, len(er.Args))
+	for _, i := range er.Args {
+		v := er.Values[i.Name]
+		values = append(values, fmt.Sprintf("%s=%s", i.Name, tableValue(i.Type, v, eventValue(i, v, textValue))))
+	}
+	return fmt.Sprintf("block %d: %s\n", er.BlockNumber, strings.Join(values, " "))
+}
+
+func (er *EventRecord) MarshalJSON() ([]byte, error) {
+	values := make(map[string]interface{}, len(er.Args))
+	for _, i := range er.Args {
+		values[i.Name] = eventValue(i, er.Values[i.Name], JSONValue)
+	}
+	return json.Marshal(&struct {
+		Event       string                 `json:"event"`
+		Args        map[string]interface{} `json:"args"`
+		BlockNumber uint64                 `json:"blockNumber"`
+		TxHash      common.Hash            `json:"txHash"`
+		LogIndex    uint                   `json:"logIndex"`
+	}{er.Event, values, er.BlockNumber, er.TxHash, er.LogIndex})
+}
+
+type SimulationRecord struct {
+	Method       string
+	Args         abi.Arguments
+	Results      []interface{}
+	Reverted     bool
+	RevertReason string
+	GasEstimate  uint64
+}
+
+func (sr *SimulationRecord) Table() string {
+	if sr.Reverted {
+		if sr.RevertReason == "" {
+			return "simulation reverted\n"
+		}
+		return fmt.Sprintf("simulation reverted: %s\n", sr.RevertReason)
+	}
+	var sb strings.Builder
+	sb.WriteString("simulation succeeded\n")
+	if len(sr.Results) > 0 {
+		sb.WriteString("returned:\n")
+		for _, i := range strings.Split(strings.TrimSuffix((&CallResult{Args: sr.Args, Results: sr.Results}).Table(), "\n"), "\n") {
+			sb.WriteString("  " + i + "\n")
+		}
+	}
+	fmt.Fprintf(&sb, "gas estimate: %d\n", sr.GasEstimate)
+	return sb.String()
+}
+
+func (sr *SimulationRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Method       string                 `json:"method"`
+		Reverted     bool                   `json:"reverted"`
+		RevertReason string                 `json:"revertReason,omitempty"`
+		Outputs      map[string]interface{} `json:"outputs,omitempty"`
+		GasEstimate  uint64                 `json:"gasEstimate,omitempty"`
+	}{sr.Method, sr.Reverted, sr.RevertReason, namedValues(sr.Args, sr.Results), sr.GasEstimate})
+}
+
+// TransactionSummary describes a transaction before it's signed
+type TransactionSummary struct {
+	From common.Address
+	// nil for deployments
+	To        *common.Address
+	ChainID   *big.Int
+	Method    string
+	Args      abi.Arguments
+	Values    []interface{}
+	Value     *big.Int
+	Nonce     uint64
+	GasLimit  uint64
+	GasPrice  *big.Int
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+}
+
+// MaxCost returns the most the transaction can cost, value included
+func (ts *TransactionSummary) MaxCost() *big.Int {
+	price := ts.GasPrice
+	if price == nil {
+		price = ts.GasFeeCap
+	}
+	r := new(big.Int)
+	if price != nil {
+		r.Mul(price, new(big.Int).SetUint64(ts.GasLimit))
+	}
+	if ts.Value != nil {
+		r.Add(r, ts.Value)
+	}
+	return r
+}
+
+func (ts *TransactionSummary) Table() string {
+	var sb strings.Builder
+	value := ts.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	fmt.Fprintf(&sb, "from: %s\n", ts.From.Hex())
+	if ts.To != nil {
+		fmt.Fprintf(&sb, "to: %s\n", ts.To.Hex())
+	} else {
+		sb.WriteString("to: contract deployment\n")
+	}
+	chainID := "-"
+	if ts.ChainID != nil {
+		chainID = ts.ChainID.String()
+	}
+	fmt.Fprintf(&sb, "chain id: %s\n", chainID)
+	fmt.Fprintf(&sb, "method: %s\n", ts.Method)
+	if len(ts.Values) > 0 {
+		sb.WriteString("arguments:\n")
+		for _, i := range strings.Split(strings.TrimSuffix((&CallResult{Args: ts.Args, Results: ts.Values}).Table(), "\n"), "\n") {
+			sb.WriteString("  " + i + "\n")
+		}
+	}
+	fmt.Fprintf(&sb, "value: %s ether\n", FormatUnits(value, 18))
+	fmt.Fprintf(&sb, "nonce: %d\n", ts.Nonce)
+	fmt.Fprintf(&sb, "gas limit: %d\n", ts.GasLimit)
+	if ts.GasPrice != nil {
+		fmt.Fprintf(&sb, "gas price: %s\n", FormatAmount(ts.GasPrice))
+	} else {
+		fmt.Fprintf(&sb, "max fee per gas: %s\n", summaryAmount(ts.GasFeeCap))
+		fmt.Fprintf(&sb, "max priority fee per gas: %s\n", summaryAmount(ts.GasTipCap))
+	}
+	if ts.GasPrice == nil && ts.GasFeeCap == nil {
+		// the fees aren't set yet
+		sb.WriteString("max cost: -\n")
+	} else {
+		fmt.Fprintf(&sb, "max cost: %s ether\n", FormatUnits(ts.MaxCost(), 18))
+	}
+	return sb.String()
+}
+
+// summaryAmount formats n, "-" if it isn't set
+func summaryAmount(n *big.Int) string {
+	if n == nil {
+		return "-"
+	}
+	return FormatAmount(n)
+}
+
+func (ts *TransactionSummary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		From      common.Address         `json:"from"`
+		To        *common.Address        `json:"to"`
+		ChainID   *decimal               `json:"chainId"`
+		Method    string                 `json:"method"`
+		Arguments map[string]interface{} `json:"arguments"`
+		Value     *decimal               `json:"value"`
+		Nonce     uint64                 `json:"nonce"`
+		GasLimit  uint64                 `json:"gasLimit"`
+		GasPrice  *decimal               `json:"gasPrice,omitempty"`
+		GasFeeCap *decimal               `json:"maxFeePerGas,omitempty"`
+		GasTipCap *decimal               `json:"maxPriorityFeePerGas,omitempty"`
+		MaxCost   *decimal               `json:"maxCost"`
+	}{
+		ts.From, ts.To, (*decimal)(ts.ChainID), ts.Method, namedValues(ts.Args, ts.Values), (*decimal)(ts.Value),
+		ts.Nonce, ts.GasLimit, (*decimal)(ts.GasPrice), (*decimal)(ts.GasFeeCap), (*decimal)(ts.GasTipCap), (*decimal)(ts.MaxCost()),
+	})
+}
+
+// RawTransactionRecord is a transaction that was built or signed but not
+// sent
+type RawTransactionRecord struct {
+	Signed bool         `json:"signed"`
+	TxHash *common.Hash `json:"txHash,omitempty"`
+	// the transaction, if it wasn't written to File
+	Raw  hexutil.Bytes `json:"raw,omitempty"`
+	File string        `json:"file,omitempty"`
+	// address of the contract created by the transaction
+	ContractAddress *common.Address `json:"contractAddress,omitempty"`
+}
+
+func (rr *RawTransactionRecord) Table() string {
+	var sb strings.Builder
+	kind := "unsigned transaction"
+	if rr.Signed {
+		kind = "signed transaction " + rr.TxHash.Hex()
+	}
+	if rr.File != "" {
+		fmt.Fprintf(&sb, "%s written to %s\n", kind, rr.File)
+	} else {
+		fmt.Fprintf(&sb, "%s:\n%s\n", kind, rr.Raw)
+	}
+	if rr.ContractAddress != nil {
+		fmt.Fprintf(&sb, "contract address: %s\n", rr.ContractAddress.Hex())
+	}
+	return sb.String()
+}
+
+type TransactionRecord struct {
+	Method string      `json:"method"`
+	TxHash common.Hash `json:"txHash"`
+}
+
+func (tr *TransactionRecord) Table() string {
+	return fmt.Sprintf("transaction sent: %s\n", tr.TxHash.Hex())
+}
+
+type ReceiptRecord struct {
+	TxHash            common.Hash     `json:"txHash"`
+	Status            uint64          `json:"status"`
+	BlockNumber       uint64          `json:"blockNumber"`
+	GasUsed           uint64          `json:"gasUsed"`
+	EffectiveGasPrice *big.Int        `json:"effectiveGasPrice"`
+	ContractAddress   *common.Address `json:"contractAddress,omitempty"`
+	RevertReason      string          `json:"revertReason,omitempty"`
+	Events            []*EventRecord  `json:"events"`
+}
+
+func (rr *ReceiptRecord) MarshalJSON() ([]byte, error) {
+	type receipt ReceiptRecord
+	return json.Marshal(&struct {
+		*receipt
+		EffectiveGasPrice *decimal `json:"effectiveGasPrice"`
+	}{(*receipt)(rr), (*decimal)(rr.EffectiveGasPrice)})
+}
+
+func (rr *ReceiptRecord) Table() string {
+	var sb strings.Builder
+	status := "success"
+	if rr.Status != types.ReceiptStatusSuccessful {
+		status = "failed"
+		if rr.RevertReason != "" {
+			status += " (" + rr.RevertReason + ")"
+		}
+	}
+	fmt.Fprintf(&sb, "transaction %s mined in block %d\n", rr.TxHash.Hex(), rr.BlockNumber)
+	fmt.Fprintf(&sb, "status: %s\n", status)
+	if rr.ContractAddress != nil {
+		fmt.Fprintf(&sb, "contract address: %s\n", rr.ContractAddress.Hex())
+	}
+	fmt.Fprintf(&sb, "gas used: %d\n", rr.GasUsed)
+	fmt.Fprintf(&sb, "effective gas price: %s\n", DisplayAmount(rr.EffectiveGasPrice))
+	fmt.Fprintf(&sb, "fee: %s\n", DisplayAmount(rr.Fee()))
+	if len(rr.Events) > 0 {
+		fmt.Fprintf(&sb, "events:\n")
+		for _, i := range rr.Events {
+			fmt.Fprintf(&sb, "  %s %s", i.Event, i.Table())
+		}
+	}
+	return sb.String()
+}
+
+type DeploymentRecord struct {
+	Address common.Address `json:"address"`
+	TxHash  *common.Hash   `json:"txHash,omitempty"`
+	// the contract was already deployed
+	Existing bool `json:"existing,omitempty"`
+}
+
+func (dr *DeploymentRecord) Table() string {
+	if dr.Existing {
+		return fmt.Sprintf("contract already deployed to address %s, skipping deployment\n", dr.Address.Hex())
+	}
+	return fmt.Sprintf("contract deployed to address %s\ntxid: %s\n", dr.Address.Hex(), dr.TxHash.Hex())
+}
+
+// VerificationRecord is the comparison of the code at Address with the
+// artifact it was deployed from
+type VerificationRecord struct {
+	Address common.Address `json:"address"`
+	Match   bool           `json:"match"`
+	// the code can't be compared with the artifact, it isn't a mismatch
+	Unverifiable bool   `json:"unverifiable,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+func (vr *VerificationRecord) Table() string {
+	if vr.Unverifiable {
+		return fmt.Sprintf("code at %s can't be verified: %s\n", vr.Address.Hex(), vr.Reason)
+	}
+	if !vr.Match {
+		return fmt.Sprintf("code at %s doesn't match the artifact: %s\n", vr.Address.Hex(), vr.Reason)
+	}
+	if vr.Reason != "" {
+		return fmt.Sprintf("code at %s matches the artifact (%s)\n", vr.Address.Hex(), vr.Reason)
+	}
+	return fmt.Sprintf("code at %s matches the artifact\n", vr.Address.Hex())
+}
+
+// PredictionRecord is the address where a contract would be deployed
+type PredictionRecord struct {
+	Address common.Address `json:"address"`
+	// signer with CREATE or factory with CREATE2
+	Deployer     common.Address `json:"deployer"`
+	Nonce        *uint64        `json:"nonce,omitempty"`
+	Salt         *common.Hash   `json:"salt,omitempty"`
+	InitCodeHash *common.Hash   `json:"initCodeHash,omitempty"`
+	// there's code at the address
+	Deployed bool `json:"deployed"`
+}
+
+func (pr *PredictionRecord) Table() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "contract address: %s\n", pr.Address.Hex())
+	if pr.Salt != nil {
+		fmt.Fprintf(&sb, "factory: %s\nsalt: %s\ninit code hash: %s\n", pr.Deployer.Hex(), pr.Salt.Hex(), pr.InitCodeHash.Hex())
+	} else {
+		fmt.Fprintf(&sb, "deployer: %s\nnonce: %d\n", pr.Deployer.Hex(), *pr.Nonce)
+	}
+	if pr.Deployed {
+		sb.WriteString("a contract is already deployed at the address\n")
+	}
+	return sb.String()
+}
+
+// decimal marshals a big.Int as a decimal string, JSON numbers lose precision
+// above 53 bits
+type decimal big.Int
+
+func (d *decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*big.Int)(d).String())
+}
+
+func namedValues(args abi.Arguments, values []interface{}) map[string]interface{} {
+	r := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		name := args[i].Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		r[name] = JSONValue(args[i].Type, v)
+	}
+	return r
+}
+
+func marshalValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("<%s>", err)
+	}
+	return string(b)
+}
+
+// tableValue marshals jv, the JSON value of v, and appends the amount in
+// human units to uint256 values when they're enabled
+func tableValue(t abi.Type, v interface{}, jv interface{}) string {
+	r := marshalValue(jv)
+	if n, ok := v.(*big.Int); ok && HumanUnits && t.T == abi.UintTy && t.Size == 256 {
+		r += " (" + FormatAmount(n) + ")"
+	}
+	return r
+}
+
+// JSONValue converts an ABI decoded value of type t into a value that
+// marshals to readable JSON: bytes become hex strings and tuples become
+// objects keyed by the component names. Integers wider than 53 bits become
+// decimal strings, JSON numbers lose precision above it
+func JSONValue(t abi.Type, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return jsonValue(t, reflect.ValueOf(v), true)
+}
+
+// textValue is JSONValue with the integers as numbers, for the table output
+// and the messages
+func textValue(t abi.Type, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return jsonValue(t, reflect.ValueOf(v), false)
+}
+
+func jsonValue(t abi.Type, v reflect.Value, quoteInts bool) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		if t.T != abi.IntTy && t.T != abi.UintTy {
+			v = v.Elem()
+		}
+	}
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		if quoteInts && t.Size > 53 {
+			return fmt.Sprint(v.Interface())
+		}
+	case abi.BytesTy, abi.FixedBytesTy, abi.FunctionTy:
+		b := make(hexutil.Bytes, v.Len())
+		for i := range b {
+			b[i] = byte(v.Index(i).Uint())
+		}
+		return b
+	case abi.SliceTy, abi.ArrayTy:
+		r := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			r = append(r, jsonValue(*t.Elem, v.Index(i), quoteInts))
+		}
+		return r
+	case abi.TupleTy:
+		r := make(map[string]interface{}, len(t.TupleElems))
+		for i, e := range t.TupleElems {
+			r[t.TupleRawNames[i]] = jsonValue(*e, v.Field(i), quoteInts)
+		}
+		return r
+	}
+	return v.Interface()
+}
+
+// eventValue returns the value of an event field converted with convert.
+// Indexed fields of dynamic types only carry the hash of the value
+func eventValue(arg abi.Argument, v interface{}, convert func(abi.Type, interface{}) interface{}) interface{} {
+	if _, ok := v.(common.Hash); ok && arg.Indexed && arg.Type.T != abi.FixedBytesTy {
+		return v
+	}
+	return convert(arg.Type, v)
+}
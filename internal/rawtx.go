@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadTransaction reads a transaction from the file fn, from stdin if fn is
+// "-", or from fn itself if it's hex. The transaction can be hex RLP, JSON or
+// a RawTransactionRecord. from and chainID are the sender and the chain
+// recorded with the unsigned transactions written by WriteTransaction, nil if
+// there aren't
+func ReadTransaction(fn string) (tx *types.Transaction, from *common.Address, chainID *big.Int, err error) {
+	var b []byte
+	switch {
+	case fn == "-":
+		b, err = ioutil.ReadAll(os.Stdin)
+	case has0xPrefix(fn):
+		b = []byte(fn)
+	default:
+		b, err = ioutil.ReadFile(fn)
+	}
+	if err != nil {
+		return nil, nil, nil, WrapError("can't read transaction", err)
+	}
+	b = bytes.TrimSpace(b)
+	tx = &types.Transaction{}
+	if len(b) > 0 && b[0] == '{' {
+		var rr RawTransactionRecord
+		if err = json.Unmarshal(b, &rr); err == nil && len(rr.Raw) > 0 {
+			b = []byte(rr.Raw.String())
+		} else {
+			if err = tx.UnmarshalJSON(b); err != nil {
+				return nil, nil, nil, WrapError("can't parse transaction", err)
+			}
+			var sender struct {
+				From    *common.Address `json:"from"`
+				ChainID *hexutil.Big    `json:"chainId"`
+			}
+			if err = json.Unmarshal(b, &sender); err != nil {
+				return nil, nil, nil, WrapError("can't parse transaction", err)
+			}
+			return tx, sender.From, (*big.Int)(sender.ChainID), nil
+		}
+	}
+	raw, err := hexutil.Decode(string(b))
+	if err != nil {
+		return nil, nil, nil, WrapError("can't parse transaction", err)
+	}
+	// legacy transactions are lists too, but with more elements
+	var env unsignedEnvelope
+	if len(raw) > 0 && raw[0] >= 0xc0 && rlp.DecodeBytes(raw, &env) == nil {
+		raw, from, chainID = env.Tx, &env.From, env.ChainID
+	}
+	if err = tx.UnmarshalBinary(raw); err != nil {
+		return nil, nil, nil, WrapError("can't parse transaction", err)
+	}
+	return tx, from, chainID, nil
+}
+
+// unsignedEnvelope is the hex RLP of the unsigned transactions, with the
+// sender the nonce and the gas were computed for and the chain id, that
+// legacy transactions don't have
+type unsignedEnvelope struct {
+	From    common.Address
+	ChainID *big.Int
+	Tx      []byte
+}
+
+// encodeTransaction returns the binary encoding of tx or, if it isn't
+// signed, of its envelope with from and chainID
+func encodeTransaction(tx *types.Transaction, from common.Address, chainID *big.Int) ([]byte, error) {
+	b, err := tx.MarshalBinary()
+	if err != nil || IsSigned(tx) {
+		return b, err
+	}
+	return rlp.EncodeToBytes(&unsignedEnvelope{From: from, ChainID: chainID, Tx: b})
+}
+
+func has0xPrefix(s string) bool {
+	return len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
+
+// WriteTransaction writes tx to fn, as JSON if fn has the .json extension or
+// as hex RLP. Unsigned transactions are written with their sender, from, and
+// the chain id of the node they were built with
+func WriteTransaction(fn string, tx *types.Transaction, from common.Address, chainID *big.Int) error {
+	var (
+		b   []byte
+		err error
+	)
+	if filepath.Ext(fn) == ".json" {
+		b, err = transactionJSON(tx, from, chainID)
+	} else {
+		if b, err = encodeTransaction(tx, from, chainID); err == nil {
+			b = []byte(hexutil.Encode(b))
+		}
+	}
+	if err != nil {
+		return WrapError("can't encode transaction", err)
+	}
+	if err = ioutil.WriteFile(fn, append(b, '\n'), 0644); err != nil {
+		return WrapError("can't write transaction", err)
+	}
+	return nil
+}
+
+// transactionJSON returns the JSON of tx, with the "from" and "chainId" fields
+// if it isn't signed
+func transactionJSON(tx *types.Transaction, from common.Address, chainID *big.Int) ([]byte, error) {
+	if IsSigned(tx) {
+		return json.MarshalIndent(tx, "", "  ")
+	}
+	b, err := json.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	m["from"] = from.Hex()
+	m["chainId"] = (*hexutil.Big)(chainID)
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// IsSigned reports whether tx has a signature
+func IsSigned(tx *types.Transaction) bool {
+	v, r, s := tx.RawSignatureValues()
+	return v.Sign() != 0 || r.Sign() != 0 || s.Sign() != 0
+}
+
+// TransactionChainID returns the chain id of tx. Unsigned legacy
+// transactions don't have it, chainID is used for them. If both are
+// available they must match
+func TransactionChainID(tx *types.Transaction, chainID *big.Int) (*big.Int, error) {
+	var r *big.Int
+	if tx.Type() != types.LegacyTxType || (IsSigned(tx) && tx.Protected()) {
+		if r = tx.ChainId(); r.Sign() == 0 {
+			r = nil
+		}
+	}
+	switch {
+	case r == nil && chainID == nil:
+		return nil, errors.New("the transaction doesn't have the chain id")
+	case r == nil:
+		return chainID, nil
+	case chainID != nil && r.Cmp(chainID) != 0:
+		return nil, errors.New("the chain id doesn't match the one of the transaction")
+	}
+	return r, nil
+}
+
+// UnsignedSigner returns a bind.SignerFn that leaves the transactions
+// unsigned, setting the chain id that signers set when signing
+func UnsignedSigner(chainID *big.Int) bind.SignerFn {
+	return func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		switch tx.Type() {
+		case types.LegacyTxType:
+			return tx, nil
+		case types.AccessListTxType:
+			return types.NewTx(&types.AccessListTx{
+				ChainID:    chainID,
+				Nonce:      tx.Nonce(),
+				GasPrice:   tx.GasPrice(),
+				Gas:        tx.Gas(),
+				To:         tx.To(),
+				Value:      tx.Value(),
+				Data:       tx.Data(),
+				AccessList: tx.AccessList(),
+			}), nil
+		case types.DynamicFeeTxType:
+			return types.NewTx(&types.DynamicFeeTx{
+				ChainID:    chainID,
+				Nonce:      tx.Nonce(),
+				GasTipCap:  tx.GasTipCap(),
+				GasFeeCap:  tx.GasFeeCap(),
+				Gas:        tx.Gas(),
+				To:         tx.To(),
+				Value:      tx.Value(),
+				Data:       tx.Data(),
+				AccessList: tx.AccessList(),
+			}), nil
+		}
+		return nil, fmt.Errorf("unsupported transaction type: %d", tx.Type())
+	}
+}
+
+// NewRawTransactionRecord returns the record of tx, written to fn if it isn't
+// empty. from is the sender, to compute the address of deployments, it's
+// recorded with chainID in the unsigned transactions
+func NewRawTransactionRecord(tx *types.Transaction, from common.Address, chainID *big.Int, fn string) *RawTransactionRecord {
+	r := &RawTransactionRecord{Signed: IsSigned(tx), File: fn}
+	if r.Signed {
+		h := tx.Hash()
+		r.TxHash = &h
+	}
+	if fn == "" {
+		r.Raw, _ = encodeTransaction(tx, from, chainID)
+	}
+	if tx.To() == nil {
+		addr := crypto.CreateAddress(from, tx.Nonce())
+		r.ContractAddress = &addr
+	}
+	return r
+}
+
+// NewRawTransactionSummary describes tx, sent by from, before signing it
+func NewRawTransactionSummary(tx *types.Transaction, from common.Address, chainID *big.Int) *TransactionSummary {
+	r := &TransactionSummary{
+		From:     from,
+		To:       tx.To(),
+		ChainID:  chainID,
+		Method:   "constructor",
+		Value:    tx.Value(),
+		Nonce:    tx.Nonce(),
+		GasLimit: tx.Gas(),
+	}
+	if tx.To() != nil {
+		r.Method = "none"
+		if data := tx.Data(); len(data) >= 4 {
+			r.Method = hexutil.Encode(data[:4])
+		}
+	}
+	if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+		r.GasPrice = tx.GasPrice()
+	} else {
+		r.GasFeeCap, r.GasTipCap = tx.GasFeeCap(), tx.GasTipCap()
+	}
+	return r
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+type ReceiptArgs struct {
+	Wait          bool
+	Confirmations uint64
+	Timeout       time.Duration
+}
+
+// NewReceiptArgs returns the default receipt arguments
+func NewReceiptArgs() *ReceiptArgs {
+	return &ReceiptArgs{Confirmations: 1, Timeout: 5 * time.Minute}
+}
+
+// AddFlags registers the flags that control the wait for receipts
+func (ra *ReceiptArgs) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&ra.Wait, "wait", ra.Wait, "wait for the transaction receipt")
+	fs.Uint64Var(&ra.Confirmations, "confirmations", ra.Confirmations, "blocks to wait for, including the one with the transaction")
+	fs.DurationVar(&ra.Timeout, "timeout", ra.Timeout, "maximum time to wait for the receipt (0 to wait forever)")
+}
+
+// WaitReceipt waits until tx is mined and has the configured confirmations
+func (ra *ReceiptArgs) WaitReceipt(cl *ethclient.Client, tx *types.Transaction) (*types.Receipt, error) {
+	ctx := context.Background()
+	if ra.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ra.Timeout)
+		defer cancel()
+	}
+	r, err := bind.WaitMined(ctx, cl, tx)
+	if err != nil {
+		return nil, WrapError("can't get receipt", err)
+	}
+	if ra.Confirmations <= 1 {
+		return r, nil
+	}
+	target := r.BlockNumber.Uint64() + ra.Confirmations - 1
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		head, err := cl.BlockNumber(ctx)
+		if err != nil {
+			return nil, WrapError("can't get block number", err)
+		}
+		if head >= target {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, WrapError("can't wait for confirmations", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	// the transaction could have been moved to another block by a reorg
+	return bind.WaitMined(ctx, cl, tx)
+}
+
+// DecodeLogs decodes the logs emitted by the contract at addr that match the
+// events in contractABI
+func DecodeLogs(contractABI *abi.ABI, addr common.Address, logs []*types.Log) ([]*EventRecord, error) {
+	bc := bind.NewBoundContract(addr, *contractABI, nil, nil, nil)
+	r := make([]*EventRecord, 0, len(logs))
+	for _, l := range logs {
+		if l.Address != addr || len(l.Topics) == 0 {
+			continue
+		}
+		ev, err := contractABI.EventByID(l.Topics[0])
+		if err != nil {
+			continue
+		}
+		values := make(map[string]interface{}, len(ev.Inputs))
+		if err = bc.UnpackLogIntoMap(values, ev.Name, *l); err != nil {
+			return nil, WrapError("can't decode log", err)
+		}
+		r = append(r, &EventRecord{
+			Event:       ev.Name,
+			Args:        ev.Inputs,
+			Values:      values,
+			BlockNumber: l.BlockNumber,
+			TxHash:      l.TxHash,
+			LogIndex:    l.Index,
+		})
+	}
+	return r, nil
+}
+
+// NewReceiptRecord builds the record of the receipt r of tx, decoding the
+// logs emitted by the contract at addr. The reason of failed transactions is
+// recovered by replaying them
+func NewReceiptRecord(cl *ethclient.Client, contractABI *abi.ABI, addr common.Address, tx *types.Transaction, r *types.Receipt) (*ReceiptRecord, error) {
+	events, err := DecodeLogs(contractABI, addr, r.Logs)
+	if err != nil {
+		return nil, err
+	}
+	gasPrice := r.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+	rr := &ReceiptRecord{
+		TxHash:            r.TxHash,
+		Status:            r.Status,
+		BlockNumber:       r.BlockNumber.Uint64(),
+		GasUsed:           r.GasUsed,
+		EffectiveGasPrice: gasPrice,
+		Events:            events,
+	}
+	if r.ContractAddress != (common.Address{}) {
+		rr.ContractAddress = &r.ContractAddress
+	}
+	if r.Status != types.ReceiptStatusSuccessful {
+		var re *RevertError
+		if err = ReplayRevert(cl, contractABI, tx, r); errors.As(err, &re) {
+			rr.RevertReason = re.Reason
+		}
+	}
+	return rr, nil
+}
+
+// Fee returns the amount paid for the transaction
+func (rr *ReceiptRecord) Fee() *big.Int {
+	return new(big.Int).Mul(rr.EffectiveGasPrice, new(big.Int).SetUint64(rr.GasUsed))
+}
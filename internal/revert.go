@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// RevertError is a reverted call or transaction with decoded revert data
+type RevertError struct {
+	Reason string
+	Data   []byte
+	err    error
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return "execution reverted"
+	}
+	return "execution reverted: " + e.Reason
+}
+
+func (e *RevertError) Unwrap() error { return e.err }
+
+// RevertData returns the revert data carried by an error returned by the node
+func RevertData(err error) ([]byte, bool) {
+	var de rpc.DataError
+	if !errors.As(err, &de) {
+		return nil, false
+	}
+	s, ok := de.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// WrapRevert decodes the revert data carried by err. It returns err as is if
+// it has no revert data
+func WrapRevert(contractABI *abi.ABI, err error) error {
+	data, ok := RevertData(err)
+	if !ok {
+		return err
+	}
+	return &RevertError{Reason: DecodeRevert(contractABI, data), Data: data, err: err}
+}
+
+// DecodeRevert decodes revert data as Error(string), Panic(uint256) or as one
+// of the custom errors declared in contractABI
+func DecodeRevert(contractABI *abi.ABI, data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	if len(data) < 4 {
+		return hexutil.Encode(data)
+	}
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		if r, err := abi.UnpackRevert(data); err == nil {
+			return r
+		}
+	case bytes.Equal(data[:4], panicSelector):
+		r, err := abi.UnpackRevert(data)
+		if err != nil {
+			break
+		}
+		code := new(big.Int).SetBytes(data[4:])
+		return fmt.Sprintf("panic %#x: %s", code, r)
+	}
+	if contractABI != nil {
+		for _, e := range contractABI.Errors {
+			if !bytes.Equal(data[:4], e.ID[:4]) {
+				continue
+			}
+			v, err := e.Unpack(data)
+			if err != nil {
+				break
+			}
+			values, _ := v.([]interface{})
+			args := make([]string, 0, len(values))
+			for i, a := range e.Inputs {
+				arg := marshalValue(textValue(a.Type, values[i]))
+				if a.Name != "" {
+					arg = a.Name + "=" + arg
+				}
+				args = append(args, arg)
+			}
+			return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))
+		}
+	}
+	return "unknown error " + hexutil.Encode(data)
+}
+
+var errRevertNotReproduced = errors.New("can't reproduce the revert")
+
+// ReplayRevert replays a reverted transaction with eth_call to recover the
+// revert reason. It's best effort: the call runs on the state of the block
+// before the one that included the transaction, without the transactions
+// that ran before it in the block, so it can succeed or revert differently
+func ReplayRevert(cl *ethclient.Client, contractABI *abi.ABI, tx *types.Transaction, r *types.Receipt) error {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return WrapError("can't recover sender", err)
+	}
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	block := new(big.Int).Sub(r.BlockNumber, big.NewInt(1))
+	if _, err = cl.CallContract(context.Background(), msg, block); err != nil {
+		return WrapRevert(contractABI, err)
+	}
+	return errRevertNotReproduced
+}
+
+// EstimateGas estimates the gas needed by msg, decoding the revert data if the
+// estimation fails
+func EstimateGas(cl *ethclient.Client, contractABI *abi.ABI, msg ethereum.CallMsg) (uint64, error) {
+	gas, err := cl.EstimateGas(context.Background(), msg)
+	if err != nil {
+		return 0, WrapError("can't estimate gas", WrapRevert(contractABI, err))
+	}
+	return gas, nil
+}
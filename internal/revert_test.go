@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const revertTestABI = `[
+	{"type": "error", "name": "Insufficient", "inputs": [
+		{"name": "available", "type": "uint256"},
+		{"name": "", "type": "address"}
+	]},
+	{"type": "error", "name": "Paused", "inputs": []}
+]`
+
+// revertData packs values with args after selector
+func revertData(t *testing.T, selector []byte, args abi.Arguments, values ...interface{}) []byte {
+	b, err := args.Pack(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append([]byte{}, selector[:4]...), b...)
+}
+
+func TestDecodeRevert(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(revertTestABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stringTy, _ := abi.NewType("string", "", nil)
+	uintTy, _ := abi.NewType("uint256", "", nil)
+	insufficient, paused := contractABI.Errors["Insufficient"], contractABI.Errors["Paused"]
+	addr := common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7")
+	for _, tt := range []struct {
+		name string
+		abi  *abi.ABI
+		data []byte
+		want string
+	}{
+		{"empty", &contractABI, nil, ""},
+		{"short", &contractABI, []byte{1, 2}, "0x0102"},
+		{"error", nil, revertData(t, errorSelector, abi.Arguments{{Type: stringTy}}, "not the owner"), "not the owner"},
+		{"panic", nil, revertData(t, panicSelector, abi.Arguments{{Type: uintTy}}, big.NewInt(0x11)), "panic 0x11: arithmetic underflow or overflow"},
+		{"unknown panic", nil, revertData(t, panicSelector, abi.Arguments{{Type: uintTy}}, big.NewInt(0x99)), "panic 0x99: unknown panic code: 0x99"},
+		{
+			"custom error",
+			&contractABI,
+			revertData(t, insufficient.ID[:], insufficient.Inputs, big.NewInt(10), addr),
+			`Insufficient(available=10, arg1="` + strings.ToLower(addr.Hex()) + `")`,
+		},
+		{"custom error without inputs", &contractABI, revertData(t, paused.ID[:], nil), "Paused()"},
+		{"custom error without abi", nil, revertData(t, paused.ID[:], nil), "unknown error " + hexutil.Encode(paused.ID[:4])},
+		{"unknown", &contractABI, []byte{1, 2, 3, 4, 5}, "unknown error 0x0102030405"},
+	} {
+		if got := DecodeRevert(tt.abi, tt.data); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
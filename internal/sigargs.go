@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/heliorosa/scui/signer"
+)
+
+type SignatureArgsParser struct {
+	backends  []signer.Backend
+	gasLimit  uint64
+	gasPrice  string
+	gasFeeCap string
+	gasTipCap string
+	legacy    bool
+	value     string
+	from      string
+}
+
+// NewSignatureArgsParser returns a parser with the default argument values
+// and the registered signer backends
+func NewSignatureArgsParser() *SignatureArgsParser {
+	return &SignatureArgsParser{
+		backends: signer.NewBackends(),
+		gasPrice: "0",
+		value:    "0",
+	}
+}
+
+// SignerEnv gives the signer backends access to the address book and the
+// password prompt
+var SignerEnv = &signer.Env{
+	ResolveAddress: ResolveAddress,
+	ReadPassword:   PromptPassword,
+}
+
+func (sap *SignatureArgsParser) NewFlagSet(name string, errorHandling flag.ErrorHandling) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, errorHandling)
+	sap.AddFlags(fs)
+	return fs
+}
+
+// AddFlags registers the signer and gas flags in an existing flag set
+func (sap *SignatureArgsParser) AddFlags(fs *flag.FlagSet) {
+	for _, b := range sap.backends {
+		b.AddFlags(fs)
+	}
+	fs.StringVar(&sap.gasPrice, "p", sap.gasPrice, "gas price (wei, or with a unit, e.g. \"20 gwei\")")
+	fs.StringVar(&sap.gasFeeCap, "maxfee", sap.gasFeeCap, "max fee per gas of dynamic fee transactions")
+	fs.StringVar(&sap.gasTipCap, "tip", sap.gasTipCap, "max priority fee per gas of dynamic fee transactions")
+	fs.BoolVar(&sap.legacy, "legacy", sap.legacy, "send a legacy transaction")
+	fs.Uint64Var(&sap.gasLimit, "l", sap.gasLimit, "gas limit")
+	fs.StringVar(&sap.value, "v", sap.value, "value to send (wei, or with a unit, e.g. \"1.5 ether\")")
+	fs.StringVar(&sap.from, "from", sap.from, "address or @name of the sender of an unsigned transaction, instead of a signer")
+}
+
+var (
+	errInvalidGasPrice = errors.New("invalid gas price")
+	errInvalidAmount   = errors.New("invalid amount")
+	errSignerMissing   = errors.New("signer arguments missing")
+)
+
+type mutuallExclusiveArgsError [2]string
+
+func (e mutuallExclusiveArgsError) Error() string {
+	return fmt.Sprintf("%s and %s are mutually exclusive", e[0], e[1])
+}
+
+func (sap *SignatureArgsParser) SignatureArgs() (*SignatureArgs, error) {
+	r := &SignatureArgs{}
+	// parse gas price
+	bigZero := big.NewInt(0)
+	if sap.gasPrice != "" {
+		gp, err := ParseAmount(sap.gasPrice)
+		if err != nil || gp.Cmp(bigZero) < 0 {
+			return nil, errInvalidGasPrice
+		} else if gp.Cmp(bigZero) > 0 {
+			r.gasPrice = gp
+		}
+	}
+	// parse dynamic fees
+	if sap.gasFeeCap != "" {
+		fc, err := ParseAmount(sap.gasFeeCap)
+		if err != nil || fc.Cmp(bigZero) <= 0 {
+			return nil, errInvalidGasPrice
+		}
+		r.gasFeeCap = fc
+	}
+	if sap.gasTipCap != "" {
+		tc, err := ParseAmount(sap.gasTipCap)
+		if err != nil || tc.Cmp(bigZero) < 0 {
+			return nil, errInvalidGasPrice
+		}
+		r.gasTipCap = tc
+	}
+	if r.gasFeeCap != nil || r.gasTipCap != nil {
+		if r.gasPrice != nil {
+			return nil, mutuallExclusiveArgsError{"-p", "-maxfee/-tip"}
+		}
+		if sap.legacy {
+			return nil, mutuallExclusiveArgsError{"-legacy", "-maxfee/-tip"}
+		}
+	}
+	r.legacy = sap.legacy
+	// parse gas limit
+	if sap.gasLimit > 0 {
+		r.gasLimit = sap.gasLimit
+	}
+	// parse amount to send
+	if sap.value != "" {
+		v, err := ParseAmount(sap.value)
+		if err != nil || v.Cmp(bigZero) < 0 {
+			return nil, errInvalidAmount
+		} else if v.Cmp(bigZero) > 0 {
+			r.value = v
+		}
+	}
+	var configured []signer.Backend
+	for _, b := range sap.backends {
+		if b.Configured() {
+			configured = append(configured, b)
+		}
+	}
+	// unsigned transactions only need the sender
+	if sap.from != "" {
+		if len(configured) > 0 {
+			return nil, fmt.Errorf("-from can't be used with a signer (%s)", configured[0].Name())
+		}
+		from, err := ResolveAddress(sap.from)
+		if err != nil {
+			return nil, WrapError("invalid sender", err)
+		}
+		r.from = from
+		return r, nil
+	}
+	switch len(configured) {
+	case 0:
+		return nil, errSignerMissing
+	case 1:
+	default:
+		names := make([]string, 0, len(configured))
+		for _, b := range configured {
+			names = append(names, b.Name())
+		}
+		return nil, fmt.Errorf("more than one signer configured: %s", strings.Join(names, ", "))
+	}
+	s, err := configured[0].FromFlags(SignerEnv)
+	if err != nil {
+		return nil, err
+	}
+	// force legacy fees for the wallets that can't sign other transactions
+	if signer.LegacyOnly(s) && !r.legacy {
+		if r.gasFeeCap != nil || r.gasTipCap != nil {
+			signer.Close(s)
+			return nil, fmt.Errorf("%s can only sign legacy transactions, use -p instead of -maxfee/-tip", s.Describe())
+		}
+		r.legacy = true
+	}
+	r.signer = s
+	return r, nil
+}
+
+type SignatureArgs struct {
+	signer signer.Signer
+	// sender of unsigned transactions, without signer
+	from      common.Address
+	gasPrice  *big.Int
+	gasFeeCap *big.Int
+	gasTipCap *big.Int
+	legacy    bool
+	gasLimit  uint64
+	value     *big.Int
+}
+
+// Signer returns the signer, nil with -from
+func (sa *SignatureArgs) Signer() signer.Signer { return sa.signer }
+
+// From returns the address of the signer, or the one given with -from
+func (sa *SignatureArgs) From() common.Address {
+	if sa.signer == nil {
+		return sa.from
+	}
+	return sa.signer.Address()
+}
+
+// Unsigned reports whether the transactions can't be signed, only built
+func (sa *SignatureArgs) Unsigned() bool { return sa.signer == nil }
+
+// Legacy reports whether a legacy transaction was requested
+func (sa *SignatureArgs) Legacy() bool { return sa.legacy }
+
+// TransactOpts returns the options to sign transactions. Without signer the
+// transactions are left unsigned and aren't sent
+func (sa *SignatureArgs) TransactOpts(chainID *big.Int) *bind.TransactOpts {
+	var r *bind.TransactOpts
+	if sa.signer == nil {
+		r = &bind.TransactOpts{
+			From:   sa.from,
+			Signer: UnsignedSigner(chainID),
+			NoSend: true,
+		}
+	} else {
+		r = signer.TransactOpts(sa.signer, chainID)
+	}
+	r.GasLimit = sa.gasLimit
+	r.GasPrice = sa.gasPrice
+	r.GasFeeCap = sa.gasFeeCap
+	r.GasTipCap = sa.gasTipCap
+	r.Value = sa.value
+	return r
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+var unitDecimals = map[string]int{
+	"wei":    0,
+	"kwei":   3,
+	"mwei":   6,
+	"gwei":   9,
+	"szabo":  12,
+	"finney": 15,
+	"ether":  18,
+	"eth":    18,
+}
+
+// HumanUnits enables the display of amounts in ether or gwei
+var HumanUnits bool
+
+// HumanUnitsUsage describes the flag that enables HumanUnits
+const HumanUnitsUsage = "display amounts in ether and gwei"
+
+// ParseAmount parses an integer in base 10 or in 0x prefixed hexadecimal, or
+// a decimal number followed by a unit, e.g. "1.5 ether" or "20 gwei"
+func ParseAmount(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	ls := strings.ToLower(strings.TrimPrefix(s, "-"))
+	i := strings.IndexFunc(s, unicode.IsLetter)
+	if i < 0 || strings.HasPrefix(ls, "0x") {
+		r, ok := ParseBigInt(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount: %s", s)
+		}
+		return r, nil
+	}
+	num, unit := strings.TrimSpace(s[:i]), strings.ToLower(strings.TrimSpace(s[i:]))
+	if num == "" {
+		return nil, fmt.Errorf("invalid amount: %s", s)
+	}
+	decimals, ok := unitDecimals[unit]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit: %s", unit)
+	}
+	neg := strings.HasPrefix(num, "-")
+	num = strings.TrimPrefix(num, "-")
+	parts := strings.SplitN(num, ".", 2)
+	intPart, fracPart := parts[0], ""
+	if len(parts) == 2 {
+		fracPart = strings.TrimRight(parts[1], "0")
+	}
+	if len(fracPart) > decimals {
+		return nil, fmt.Errorf("too many decimal places for %s: %s", unit, s)
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	digits := intPart + fracPart + strings.Repeat("0", decimals-len(fracPart))
+	r, ok := new(big.Int).SetString(digits, 10)
+	if !ok || strings.ContainsAny(digits, "+-") {
+		return nil, fmt.Errorf("invalid amount: %s", s)
+	}
+	if neg {
+		r.Neg(r)
+	}
+	return r, nil
+}
+
+// FormatAmount formats a wei amount in ether, gwei or wei, whichever fits best
+func FormatAmount(v *big.Int) string {
+	abs := new(big.Int).Abs(v)
+	switch {
+	case abs.Cmp(big.NewInt(1e15)) >= 0:
+		return FormatUnits(v, 18) + " ether"
+	case abs.Cmp(big.NewInt(1e6)) >= 0:
+		return FormatUnits(v, 9) + " gwei"
+	}
+	return v.String() + " wei"
+}
+
+// FormatUnits formats v as a decimal number with the given decimal places,
+// without trailing zeros
+func FormatUnits(v *big.Int, decimals int) string {
+	s := new(big.Int).Abs(v).String()
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	intPart, fracPart := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+	if v.Sign() < 0 {
+		intPart = "-" + intPart
+	}
+	if fracPart == "" {
+		return intPart
+	}
+	return intPart + "." + fracPart
+}
+
+// DisplayAmount formats v in human units if they are enabled, in wei otherwise
+func DisplayAmount(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	if HumanUnits {
+		return FormatAmount(v)
+	}
+	return v.String()
+}
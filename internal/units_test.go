@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want string
+	}{
+		{"1000", "1000"},
+		{"0x3e8", "1000"},
+		{"-5", "-5"},
+		{"1.5 ether", "1500000000000000000"},
+		{"1.5ether", "1500000000000000000"},
+		{"20 gwei", "20000000000"},
+		{"20 GWei", "20000000000"},
+		{".5 eth", "500000000000000000"},
+		{"1.000000001 gwei", "1000000001"},
+		{"-0.25 ether", "-250000000000000000"},
+		{"7 wei", "7"},
+		{"1.50 wei", ""},
+		{"1.0000000001 gwei", ""},
+		{"--5", ""},
+		{"-+5", ""},
+		{"0x-5", ""},
+		{"--1 ether", ""},
+		{"+1 ether", ""},
+		{"1.2.3 ether", ""},
+		{"ether", ""},
+		{"1 bitcoin", ""},
+		{"", ""},
+	} {
+		r, err := ParseAmount(tt.s)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%q: got %s, want an error", tt.s, r)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tt.s, err)
+			continue
+		}
+		if want, _ := new(big.Int).SetString(tt.want, 10); r.Cmp(want) != 0 {
+			t.Errorf("%q: got %s, want %s", tt.s, r, tt.want)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	for _, tt := range []struct {
+		v    string
+		want string
+	}{
+		{"0", "0 wei"},
+		{"999999", "999999 wei"},
+		{"20000000000", "20 gwei"},
+		{"1500000000000000000", "1.5 ether"},
+		{"-1000000000000000", "-0.001 ether"},
+	} {
+		v, _ := new(big.Int).SetString(tt.v, 10)
+		if got := FormatAmount(v); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
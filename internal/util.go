@@ -1,48 +1,30 @@
 package internal
 
 import (
-	"crypto/ecdsa"
 	"fmt"
 	"os"
 	"syscall"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/accounts/keystore"
-	"github.com/ethereum/go-ethereum/crypto"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
 func ErrorExit(code int, f string, a ...interface{}) {
+	// keep the results printed before the error valid json
+	for _, p := range printers {
+		p.Flush()
+	}
 	fmt.Fprintf(os.Stderr, f, a...)
 	os.Exit(code)
 }
 
+// ReadABI reads the abi from a bare abi file or from an artifact
 func ReadABI(fn string) (*abi.ABI, error) {
-	f, err := os.Open(fn)
-	if err != nil {
-		return nil, WrapError("can't open file", err)
-	}
-	defer f.Close()
-	r, err := abi.JSON(f)
-	if err != nil {
-		return nil, WrapError("can't parse abi", err)
-	}
-	return &r, nil
-}
-
-func ParseKey(b []byte, encrypted bool, password string) (*ecdsa.PrivateKey, error) {
-	if encrypted {
-		ksk, err := keystore.DecryptKey(b, password)
-		if err != nil {
-			return nil, WrapError("can't decrypt key", err)
-		}
-		return ksk.PrivateKey, nil
-	}
-	k, err := crypto.HexToECDSA(string(b))
+	a, err := ReadArtifact(fn)
 	if err != nil {
-		return nil, WrapError("can't import key", err)
+		return nil, err
 	}
-	return k, nil
+	return a.ABI, nil
 }
 
 func PromptPassword() (string, error) {
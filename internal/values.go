@@ -0,0 +1,362 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// IsComposite reports whether values of type t are arrays, slices or tuples
+func IsComposite(t abi.Type) bool {
+	return t.T == abi.SliceTy || t.T == abi.ArrayTy || t.T == abi.TupleTy
+}
+
+// ParseValue parses the text representation of a value of type t. Arrays,
+// slices and tuples are expected as a JSON literal, tuples either as an
+// object keyed by the component names or as an array of components
+func ParseValue(t abi.Type, s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	if !IsComposite(t) {
+		// strings can be quoted to keep blanks or to enter an empty string
+		if t.T == abi.StringTy && len(s) > 1 && s[0] == '"' && s[len(s)-1] == '"' {
+			if err := json.Unmarshal([]byte(s), &s); err != nil {
+				return nil, &valueError{t: t, v: s, m: err.Error()}
+			}
+		}
+		return ConvertValue(t, s)
+	}
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, WrapError("can't parse JSON", err)
+	}
+	return ConvertValue(t, v)
+}
+
+// ConvertValue converts v to the go type used by the abi package to encode
+// values of type t. v can be a value of that type, a decoded JSON value or,
+// for elementary types, its text representation
+func ConvertValue(t abi.Type, v interface{}) (interface{}, error) {
+	r, err := convertValue(t, v)
+	if err != nil {
+		return nil, err
+	}
+	return r.Interface(), nil
+}
+
+// ConvertArguments converts the values of args, given as a slice in order or
+// as a map keyed by argument name
+func ConvertArguments(args abi.Arguments, v interface{}) ([]interface{}, error) {
+	var values []interface{}
+	switch av := v.(type) {
+	case nil:
+	case []interface{}:
+		values = av
+	case map[string]interface{}:
+		values = make([]interface{}, 0, len(args))
+		for i, a := range args {
+			if a.Name == "" {
+				return nil, fmt.Errorf("argument %d has no name, the arguments must be given in order", i)
+			}
+			val, ok := av[a.Name]
+			if !ok {
+				return nil, fmt.Errorf("missing argument %d %s (%s)", i, a.Name, a.Type.String())
+			}
+			values = append(values, val)
+		}
+		if len(av) != len(values) {
+			unknown := make([]string, 0, len(av))
+			for n := range av {
+				if !hasArgument(args, n) {
+					unknown = append(unknown, n)
+				}
+			}
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("unknown arguments: %s", strings.Join(unknown, ", "))
+		}
+	default:
+		return nil, errors.New("expecting an array or an object with the arguments")
+	}
+	if len(values) != len(args) {
+		return nil, fmt.Errorf("expecting %d arguments, %d provided", len(args), len(values))
+	}
+	r := make([]interface{}, 0, len(args))
+	for i, a := range args {
+		cv, err := ConvertValue(a.Type, values[i])
+		if err != nil {
+			return nil, WrapError(fmt.Sprintf("argument %d %s (%s)", i, a.Name, a.Type.String()), err)
+		}
+		r = append(r, cv)
+	}
+	return r, nil
+}
+
+// ParseArguments parses the text representation of the values of args, in
+// order
+func ParseArguments(args abi.Arguments, values []string) ([]interface{}, error) {
+	if len(values) != len(args) {
+		return nil, fmt.Errorf("expecting %d arguments, %d provided", len(args), len(values))
+	}
+	r := make([]interface{}, 0, len(args))
+	for i, a := range args {
+		v, err := ParseValue(a.Type, values[i])
+		if err != nil {
+			return nil, WrapError(fmt.Sprintf("argument %d %s (%s)", i, a.Name, a.Type.String()), err)
+		}
+		r = append(r, v)
+	}
+	return r, nil
+}
+
+func hasArgument(args abi.Arguments, name string) bool {
+	for _, i := range args {
+		if i.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type valueError struct {
+	t abi.Type
+	v interface{}
+	m string
+}
+
+func (e *valueError) Error() string {
+	if e.m != "" {
+		return fmt.Sprintf("invalid %s value %v: %s", e.t.String(), e.v, e.m)
+	}
+	return fmt.Sprintf("invalid %s value %v", e.t.String(), e.v)
+}
+
+func convertValue(t abi.Type, v interface{}) (reflect.Value, error) {
+	ty := t.GetType()
+	if v != nil && reflect.TypeOf(v) == ty {
+		return reflect.ValueOf(v), nil
+	}
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		return convertInt(t, v)
+	case abi.BoolTy:
+		switch b := v.(type) {
+		case bool:
+			return reflect.ValueOf(b), nil
+		case string:
+			r, err := strconv.ParseBool(b)
+			if err != nil {
+				return reflect.Value{}, &valueError{t: t, v: v}
+			}
+			return reflect.ValueOf(r), nil
+		}
+	case abi.StringTy:
+		s, ok := v.(string)
+		if !ok {
+			break
+		}
+		return reflect.ValueOf(s), nil
+	case abi.AddressTy:
+		s, ok := v.(string)
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(s, "@") {
+			a, err := ResolveAddress(s)
+			if err != nil {
+				return reflect.Value{}, &valueError{t: t, v: v, m: err.Error()}
+			}
+			return reflect.ValueOf(a), nil
+		}
+		if !common.IsHexAddress(s) {
+			break
+		}
+		return reflect.ValueOf(common.HexToAddress(s)), nil
+	case abi.BytesTy:
+		s, ok := v.(string)
+		if !ok {
+			break
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return reflect.Value{}, &valueError{t: t, v: v, m: err.Error()}
+		}
+		return reflect.ValueOf(b), nil
+	case abi.FixedBytesTy, abi.FunctionTy:
+		s, ok := v.(string)
+		if !ok {
+			break
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return reflect.Value{}, &valueError{t: t, v: v, m: err.Error()}
+		}
+		if len(b) != ty.Len() {
+			return reflect.Value{}, &valueError{t: t, v: v, m: fmt.Sprintf("expecting %d bytes", ty.Len())}
+		}
+		r := reflect.New(ty).Elem()
+		reflect.Copy(r, reflect.ValueOf(b))
+		return r, nil
+	case abi.SliceTy, abi.ArrayTy:
+		rv := reflect.ValueOf(v)
+		if v == nil || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+			break
+		}
+		var r reflect.Value
+		if t.T == abi.SliceTy {
+			r = reflect.MakeSlice(ty, rv.Len(), rv.Len())
+		} else if rv.Len() != t.Size {
+			return reflect.Value{}, &valueError{t: t, v: v, m: fmt.Sprintf("expecting %d elements", t.Size)}
+		} else {
+			r = reflect.New(ty).Elem()
+		}
+		for i := 0; i < rv.Len(); i++ {
+			e, err := convertValue(*t.Elem, rv.Index(i).Interface())
+			if err != nil {
+				return reflect.Value{}, WrapError(fmt.Sprintf("element %d", i), err)
+			}
+			r.Index(i).Set(e)
+		}
+		return r, nil
+	case abi.TupleTy:
+		var fields []interface{}
+		switch tv := v.(type) {
+		case []interface{}:
+			if len(tv) != len(t.TupleElems) {
+				return reflect.Value{}, &valueError{t: t, v: v, m: fmt.Sprintf("expecting %d components", len(t.TupleElems))}
+			}
+			fields = tv
+		case map[string]interface{}:
+			fields = make([]interface{}, 0, len(t.TupleElems))
+			for _, n := range t.TupleRawNames {
+				f, ok := tv[n]
+				if !ok {
+					return reflect.Value{}, &valueError{t: t, v: v, m: fmt.Sprintf("missing component %s", n)}
+				}
+				fields = append(fields, f)
+			}
+			if len(tv) != len(fields) {
+				return reflect.Value{}, &valueError{t: t, v: v, m: "unknown components"}
+			}
+		default:
+			return reflect.Value{}, &valueError{t: t, v: v}
+		}
+		r := reflect.New(ty).Elem()
+		for i, e := range t.TupleElems {
+			f, err := convertValue(*e, fields[i])
+			if err != nil {
+				return reflect.Value{}, WrapError(fmt.Sprintf("component %s", t.TupleRawNames[i]), err)
+			}
+			r.Field(i).Set(f)
+		}
+		return r, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported type: %s", t.String())
+	}
+	return reflect.Value{}, &valueError{t: t, v: v}
+}
+
+func convertInt(t abi.Type, v interface{}) (reflect.Value, error) {
+	var (
+		n  *big.Int
+		ok bool
+	)
+	switch iv := v.(type) {
+	case string:
+		var err error
+		if n, err = ParseAmount(iv); err != nil {
+			return reflect.Value{}, &valueError{t: t, v: v, m: err.Error()}
+		}
+		ok = true
+	case json.Number:
+		n, ok = ParseBigInt(iv.String())
+	case *big.Int:
+		n, ok = iv, iv != nil
+	}
+	if !ok {
+		return reflect.Value{}, &valueError{t: t, v: v}
+	}
+	// check range
+	var min, max *big.Int
+	if t.T == abi.UintTy {
+		min = new(big.Int)
+		max = new(big.Int).Lsh(big.NewInt(1), uint(t.Size))
+	} else {
+		max = new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		min = new(big.Int).Neg(max)
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+		return reflect.Value{}, &valueError{t: t, v: v, m: "out of range"}
+	}
+	ty := t.GetType()
+	if ty == reflect.TypeOf(n) {
+		return reflect.ValueOf(n), nil
+	}
+	r := reflect.New(ty).Elem()
+	if t.T == abi.UintTy {
+		r.SetUint(n.Uint64())
+	} else {
+		r.SetInt(n.Int64())
+	}
+	return r, nil
+}
+
+// ParseBigInt parses a base 10 or a 0x prefixed hexadecimal integer
+func ParseBigInt(s string) (*big.Int, bool) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	var (
+		r    *big.Int
+		ok   bool
+		base = 10
+	)
+	if len(s) > 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		s = s[2:]
+		base = 16
+	}
+	// big.Int accepts a sign of its own, which would allow "--5" or "0x-5"
+	if len(s) > 0 && s[0] != '+' && s[0] != '-' {
+		r, ok = new(big.Int).SetString(s, base)
+	}
+	if !ok {
+		return nil, false
+	}
+	if neg {
+		r.Neg(r)
+	}
+	return r, true
+}
+
+// FormatType returns the type of t with the component names of tuples
+func FormatType(t abi.Type) string {
+	switch t.T {
+	case abi.TupleTy:
+		var b strings.Builder
+		b.WriteString("(")
+		for i, e := range t.TupleElems {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%s %s", FormatType(*e), t.TupleRawNames[i])
+		}
+		b.WriteString(")")
+		return b.String()
+	case abi.SliceTy:
+		return FormatType(*t.Elem) + "[]"
+	case abi.ArrayTy:
+		return fmt.Sprintf("%s[%d]", FormatType(*t.Elem), t.Size)
+	}
+	return t.String()
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// VerifyCode compares code, deployed from the artifact, with its deployed
+// bytecode. The metadata hash appended by solc and the immutable variables
+// are ignored. The record has whether they match and, if they don't, the
+// reason. Its address isn't set
+func (a *Artifact) VerifyCode(code []byte) (*VerificationRecord, error) {
+	expected, err := DecodeBytecode(a.DeployedBytecode)
+	if err != nil {
+		return nil, WrapError("invalid deployed bytecode", err)
+	}
+	if len(code) == 0 {
+		return &VerificationRecord{Reason: "no code at the address"}, nil
+	}
+	actual := make([]byte, len(code))
+	copy(actual, code)
+	// immutable variables are set by the constructor
+	for _, refs := range a.ImmutableReferences {
+		for _, i := range refs {
+			maskCode(expected, i)
+			maskCode(actual, i)
+		}
+	}
+	// libraries start with PUSH20 of their own address, zero in the artifact
+	if len(expected) > 21 && expected[0] == 0x73 && bytes.Equal(expected[1:21], make([]byte, 20)) {
+		maskCode(actual, LinkReference{Start: 1, Length: 20})
+	}
+	expected, expectedMeta := splitMetadata(expected)
+	actual, actualMeta := splitMetadata(actual)
+	if len(expected) != len(actual) {
+		return &VerificationRecord{Reason: fmt.Sprintf("code size is %d bytes, expecting %d", len(actual), len(expected))}, nil
+	}
+	// immutable variables are zero in the artifacts without immutable
+	// references, like the ones of hardhat and truffle
+	diff, zerosOnly := -1, true
+	for i := range expected {
+		if expected[i] != actual[i] {
+			if diff < 0 {
+				diff = i
+			}
+			if expected[i] != 0 {
+				zerosOnly = false
+				break
+			}
+		}
+	}
+	switch {
+	case diff >= 0 && zerosOnly && len(a.ImmutableReferences) == 0:
+		return &VerificationRecord{
+			Unverifiable: true,
+			Reason:       fmt.Sprintf("artifact lacks immutableReferences, the code differs from byte %d only where the artifact has zeros, like immutable variables", diff),
+		}, nil
+	case diff >= 0:
+		return &VerificationRecord{Reason: fmt.Sprintf("code differs at byte %d", diff)}, nil
+	case !bytes.Equal(expectedMeta, actualMeta):
+		return &VerificationRecord{Match: true, Reason: "metadata hash differs, the sources or the compiler settings changed"}, nil
+	}
+	return &VerificationRecord{Match: true}, nil
+}
+
+// maskCode zeroes the bytes of code in ref
+func maskCode(code []byte, ref LinkReference) {
+	if ref.Start >= 0 && ref.Length > 0 && ref.Start+ref.Length <= len(code) {
+		copy(code[ref.Start:], make([]byte, ref.Length))
+	}
+}
+
+// splitMetadata splits the CBOR metadata appended by solc from code. Its
+// length is in the last two bytes
+func splitMetadata(code []byte) ([]byte, []byte) {
+	if len(code) < 2 {
+		return code, nil
+	}
+	n := int(code[len(code)-2])<<8 | int(code[len(code)-1])
+	start := len(code) - 2 - n
+	// the metadata is a CBOR map
+	if n == 0 || start < 0 || code[start]&0xe0 != 0xa0 {
+		return code, nil
+	}
+	return code[:start], code[start:]
+}
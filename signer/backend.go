@@ -0,0 +1,58 @@
+package signer
+
+import (
+	"flag"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Backend configures a kind of signer, from flags in the command line or by
+// asking the user in the console
+type Backend interface {
+	// Name identifies the backend in the signer menu
+	Name() string
+	Description() string
+	// AddFlags registers the flags that configure the backend
+	AddFlags(fs *flag.FlagSet)
+	// Configured reports whether any of the flags was set
+	Configured() bool
+	// FromFlags returns the signer configured by the flags
+	FromFlags(env *Env) (Signer, error)
+	// FromPrompt asks the user for the configuration of the signer
+	FromPrompt(env *Env, p Prompter) (Signer, error)
+}
+
+// Env has the functions of the program that the backends use
+type Env struct {
+	// ResolveAddress parses an address or an @name from the address book
+	ResolveAddress func(s string) (common.Address, error)
+	// ReadPassword reads a password from the terminal
+	ReadPassword func() (string, error)
+}
+
+// Prompter asks the user for input in the console. The second result of
+// YesNo and Choice is false if the answer isn't valid
+type Prompter interface {
+	Text(msg string) string
+	Password(msg string) (string, error)
+	Filename(msg string) (string, error)
+	Directory(msg string) (string, error)
+	YesNo(msg string, def bool) (bool, bool)
+	Choice(msg string, def string, choices []string) (string, bool)
+}
+
+var backends []func() Backend
+
+// Register adds a backend. newBackend returns a new instance, every flag set
+// gets its own
+func Register(newBackend func() Backend) { backends = append(backends, newBackend) }
+
+// NewBackends returns new instances of the registered backends, in the order
+// they were registered
+func NewBackends() []Backend {
+	r := make([]Backend, 0, len(backends))
+	for _, i := range backends {
+		r = append(r, i())
+	}
+	return r
+}
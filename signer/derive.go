@@ -0,0 +1,88 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultDerivationPath is the derivation path of the accounts of wallets, x
+// is replaced by the index of the account
+const DefaultDerivationPath = "m/44'/60'/x'/0/0"
+
+// derivation paths offered in the console
+var derivationPaths = []string{DefaultDerivationPath, "m/44'/60'/0'/x", MnemonicDerivationPath}
+
+var errSignerAddressNotfound = errors.New("signer address not found")
+
+// derivationPath returns the path n of the derivation path template dp
+func derivationPath(dp string, n int) (accounts.DerivationPath, error) {
+	r, err := accounts.ParseDerivationPath(strings.ReplaceAll(dp, "x", fmt.Sprint(n)))
+	if err != nil {
+		return nil, fmt.Errorf("can't parse derivation path: %w", err)
+	}
+	return r, nil
+}
+
+// deriveFunc derives the address n of the derivation path template dp
+type deriveFunc func(dp string, n int) (common.Address, error)
+
+// findIndex returns the index of addr among the first addresses of dp, or 0
+// if addr is nil
+func findIndex(derive deriveFunc, dp string, addr *common.Address) (int, error) {
+	if addr == nil {
+		return 0, nil
+	}
+	for i := 0; i < 5; i++ {
+		a, err := derive(dp, i)
+		if err != nil {
+			return 0, err
+		}
+		if a == *addr {
+			return i, nil
+		}
+	}
+	return 0, errSignerAddressNotfound
+}
+
+var errAborted = errors.New("aborted")
+
+// promptIndex asks for the derivation path template, def by default, and the
+// index of the address, showing the addresses 5 at a time
+func promptIndex(derive deriveFunc, p Prompter, def string) (string, int, error) {
+	choices := append(append(make([]string, 0, len(derivationPaths)+1), derivationPaths...), "custom")
+	dp, ok := p.Choice("derivation path", def, choices)
+	if !ok {
+		return "", 0, errAborted
+	}
+	if dp == "custom" {
+		dp = p.Text("custom derivation path: ")
+	}
+	addrs := make([]string, 0, 5)
+	for {
+		for i := len(addrs); i < cap(addrs); i++ {
+			a, err := derive(dp, i)
+			if err != nil {
+				return "", 0, err
+			}
+			addrs = append(addrs, a.Hex())
+		}
+		choices := append(append(make([]string, 0, len(addrs)+1), addrs...), "more")
+		a, ok := p.Choice("address", addrs[0], choices)
+		if !ok {
+			return "", 0, errAborted
+		}
+		if a == "more" {
+			addrs = append(make([]string, 0, cap(addrs)+5), addrs...)
+			continue
+		}
+		for i, addr := range addrs {
+			if addr == a {
+				return dp, i, nil
+			}
+		}
+	}
+}
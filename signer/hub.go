@@ -0,0 +1,245 @@
+package signer
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func init() {
+	Register(func() Backend { return NewHubBackend("ledger", "sign with ledger", "w", openLedgerHub) })
+	Register(func() Backend {
+		return NewHubBackend("trezor", "sign with trezor (legacy transactions only)", "trezor", openTrezorHub)
+	})
+	Register(func() Backend {
+		return NewHubBackend("hardware", "sign with any hardware wallet", "hw", func() (Hub, error) {
+			return openHubs(openLedgerHub, openTrezorHub)
+		})
+	})
+}
+
+// Hub lists the connected hardware wallets, like the usbwallet hubs
+type Hub interface {
+	Wallets() []accounts.Wallet
+}
+
+func openLedgerHub() (Hub, error) {
+	h, err := usbwallet.NewLedgerHub()
+	if err != nil {
+		return nil, fmt.Errorf("can't open ledger hub: %w", err)
+	}
+	return h, nil
+}
+
+// openTrezorHub opens the hubs of the trezors with HID and with WebUSB
+func openTrezorHub() (Hub, error) {
+	return openHubs(
+		func() (Hub, error) {
+			h, err := usbwallet.NewTrezorHubWithHID()
+			if err != nil {
+				return nil, fmt.Errorf("can't open trezor hid hub: %w", err)
+			}
+			return h, nil
+		},
+		func() (Hub, error) {
+			h, err := usbwallet.NewTrezorHubWithWebUSB()
+			if err != nil {
+				return nil, fmt.Errorf("can't open trezor webusb hub: %w", err)
+			}
+			return h, nil
+		},
+	)
+}
+
+// multiHub lists the wallets of several hubs
+type multiHub []Hub
+
+func (mh multiHub) Wallets() []accounts.Wallet {
+	var r []accounts.Wallet
+	for _, h := range mh {
+		r = append(r, h.Wallets()...)
+	}
+	return r
+}
+
+// openHubs opens the hubs that can be opened, it fails if none can
+func openHubs(open ...func() (Hub, error)) (Hub, error) {
+	var (
+		r    multiHub
+		errs []error
+	)
+	for _, o := range open {
+		h, err := o()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r = append(r, h)
+	}
+	if len(r) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return r, nil
+}
+
+var errWalletNotFound = errors.New("wallet not found")
+
+// hubBackend configures the signers of the wallets of a hub
+type hubBackend struct {
+	name        string
+	description string
+	enableFlag  string
+	newHub      func() (Hub, error)
+	enabled     bool
+	fs          *flag.FlagSet
+}
+
+// NewHubBackend returns a backend that signs with the wallets of the hub
+// returned by newHub, enabled with the flag enableFlag
+func NewHubBackend(name, description, enableFlag string, newHub func() (Hub, error)) Backend {
+	return &hubBackend{
+		name:        name,
+		description: description,
+		enableFlag:  enableFlag,
+		newHub:      newHub,
+	}
+}
+
+func (hb *hubBackend) Name() string        { return hb.name }
+func (hb *hubBackend) Description() string { return hb.description }
+
+func (hb *hubBackend) AddFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&hb.enabled, hb.enableFlag, hb.enabled, hb.description)
+	// the hardware wallets share the derivation path and the address
+	if fs.Lookup("d") == nil {
+		fs.String("d", DefaultDerivationPath, "derivation path of the hardware wallet accounts")
+		fs.String("a", "", "address or @name from the address book (empty to use the first in the derivation path)")
+	}
+	hb.fs = fs
+}
+
+func (hb *hubBackend) Configured() bool { return hb.enabled }
+
+// pickWallet returns the only wallet of the hub, or the one picked by pick if
+// there are more
+func (hb *hubBackend) pickWallet(pick func(wallets []accounts.Wallet) (accounts.Wallet, error)) (accounts.Wallet, error) {
+	hub, err := hb.newHub()
+	if err != nil {
+		return nil, err
+	}
+	switch wallets := hub.Wallets(); len(wallets) {
+	case 0:
+		return nil, fmt.Errorf("%s: %w", hb.name, errWalletNotFound)
+	case 1:
+		return wallets[0], nil
+	default:
+		return pick(wallets)
+	}
+}
+
+// openWallet opens w, asking with secret for the pin and the passphrase of
+// the trezors
+func openWallet(w accounts.Wallet, secret func(msg string) (string, error)) error {
+	err := w.Open("")
+	for {
+		var msg string
+		switch {
+		case errors.Is(err, usbwallet.ErrTrezorPINNeeded):
+			msg = "pin (positions in the matrix shown by the trezor): "
+		case errors.Is(err, usbwallet.ErrTrezorPassphraseNeeded):
+			msg = "passphrase (empty for none): "
+		case err != nil:
+			return fmt.Errorf("can't open wallet: %w", err)
+		default:
+			return nil
+		}
+		s, serr := secret(msg)
+		if serr != nil {
+			w.Close()
+			return serr
+		}
+		err = w.Open(s)
+	}
+}
+
+// newSigner derives the account n of dp and returns its signer, the wallet is
+// closed on error
+func (hb *hubBackend) newSigner(w accounts.Wallet, dp string, n int) (Signer, error) {
+	acc, err := deriveAccount(w, dp, n)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return NewWallet(w, acc), nil
+}
+
+func (hb *hubBackend) FromFlags(env *Env) (Signer, error) {
+	w, err := hb.pickWallet(func([]accounts.Wallet) (accounts.Wallet, error) {
+		return nil, errors.New("more than one hardware wallet detected")
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = openWallet(w, func(msg string) (string, error) {
+		fmt.Fprint(os.Stderr, msg)
+		return env.ReadPassword()
+	})
+	if err != nil {
+		return nil, err
+	}
+	dp := hb.fs.Lookup("d").Value.String()
+	var addr *common.Address
+	if s := hb.fs.Lookup("a").Value.String(); s != "" {
+		a, err := env.ResolveAddress(s)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		addr = &a
+	}
+	n, err := findIndex(walletDerive(w), dp, addr)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return hb.newSigner(w, dp, n)
+}
+
+func (hb *hubBackend) FromPrompt(env *Env, p Prompter) (Signer, error) {
+	w, err := hb.pickWallet(func(wallets []accounts.Wallet) (accounts.Wallet, error) {
+		fmt.Printf("found more than one wallet. choose one.\n")
+		wm := make(map[string]accounts.Wallet, len(wallets))
+		wu := make([]string, 0, len(wallets))
+		for _, i := range wallets {
+			wm[i.URL().String()] = i
+			wu = append(wu, i.URL().String())
+		}
+		u, ok := p.Choice("wallet", wu[0], wu)
+		if !ok {
+			return nil, errAborted
+		}
+		return wm[u], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = openWallet(w, p.Password); err != nil {
+		return nil, err
+	}
+	if st, err := w.Status(); err != nil {
+		fmt.Printf("can't get status: %s\n", err)
+	} else {
+		fmt.Printf("wallet status: %s\n", st)
+	}
+	dp, n, err := promptIndex(walletDerive(w), p, DefaultDerivationPath)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return hb.newSigner(w, dp, n)
+}
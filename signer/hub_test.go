@@ -0,0 +1,252 @@
+package signer
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeWallet derives addresses from its url and the path. If pin is set it
+// asks for it, and then for passphrase if it's set, like a trezor
+type fakeWallet struct {
+	accounts.Wallet
+	url        accounts.URL
+	pin        string
+	passphrase string
+	unlocked   bool
+	open       bool
+}
+
+func newFakeWallet(scheme, path string) *fakeWallet {
+	return &fakeWallet{url: accounts.URL{Scheme: scheme, Path: path}}
+}
+
+func (fw *fakeWallet) URL() accounts.URL { return fw.url }
+
+func (fw *fakeWallet) Status() (string, error) { return "ok", nil }
+
+func (fw *fakeWallet) Open(secret string) error {
+	switch {
+	case fw.pin == "" || fw.open:
+	case !fw.unlocked && secret == "":
+		return usbwallet.ErrTrezorPINNeeded
+	case !fw.unlocked && secret != fw.pin:
+		return errors.New("invalid pin")
+	case !fw.unlocked:
+		fw.unlocked = true
+		if fw.passphrase != "" {
+			return usbwallet.ErrTrezorPassphraseNeeded
+		}
+	case secret != fw.passphrase:
+		return errors.New("invalid passphrase")
+	}
+	fw.open = true
+	return nil
+}
+
+func (fw *fakeWallet) Close() error {
+	fw.open = false
+	return nil
+}
+
+func (fw *fakeWallet) pathAddress(path accounts.DerivationPath) common.Address {
+	return common.BytesToAddress(crypto.Keccak256([]byte(fw.url.String() + path.String())))
+}
+
+// address returns the address n of the derivation path template dp
+func (fw *fakeWallet) address(dp string, n int) common.Address {
+	p, _ := derivationPath(dp, n)
+	return fw.pathAddress(p)
+}
+
+func (fw *fakeWallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	if !fw.open {
+		return accounts.Account{}, errors.New("wallet closed")
+	}
+	return accounts.Account{Address: fw.pathAddress(path), URL: fw.url}, nil
+}
+
+func (fw *fakeWallet) SignTx(acc accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return tx, nil
+}
+
+type fakeHub []accounts.Wallet
+
+func (fh fakeHub) Wallets() []accounts.Wallet { return fh }
+
+// fakePrompter answers with the values of choices and secrets, in order
+type fakePrompter struct {
+	Prompter
+	choices []string
+	secrets []string
+}
+
+func (fp *fakePrompter) Choice(msg string, def string, choices []string) (string, bool) {
+	if len(fp.choices) == 0 {
+		return "", false
+	}
+	r := fp.choices[0]
+	fp.choices = fp.choices[1:]
+	return r, true
+}
+
+func (fp *fakePrompter) Password(msg string) (string, error) {
+	if len(fp.secrets) == 0 {
+		return "", errAborted
+	}
+	r := fp.secrets[0]
+	fp.secrets = fp.secrets[1:]
+	return r, nil
+}
+
+func testEnv(secrets ...string) *Env {
+	return &Env{
+		ResolveAddress: func(s string) (common.Address, error) {
+			if !common.IsHexAddress(s) {
+				return common.Address{}, fmt.Errorf("invalid address: %s", s)
+			}
+			return common.HexToAddress(s), nil
+		},
+		ReadPassword: func() (string, error) {
+			if len(secrets) == 0 {
+				return "", errAborted
+			}
+			r := secrets[0]
+			secrets = secrets[1:]
+			return r, nil
+		},
+	}
+}
+
+func testHubBackend(t *testing.T, args []string, wallets ...accounts.Wallet) Backend {
+	hb := NewHubBackend("test", "sign with a test wallet", "test", func() (Hub, error) {
+		return fakeHub(wallets), nil
+	})
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	hb.AddFlags(fs)
+	if err := fs.Parse(append([]string{"-test"}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	return hb
+}
+
+func TestHubBackendFromFlags(t *testing.T) {
+	w := newFakeWallet("ledger", "1")
+	trezor := newFakeWallet(usbwallet.TrezorScheme, "2")
+	trezor.pin, trezor.passphrase = "1234", "secret"
+	for _, tt := range []struct {
+		name    string
+		wallets []accounts.Wallet
+		args    []string
+		secrets []string
+		want    common.Address
+		err     error
+	}{
+		{name: "one wallet", wallets: []accounts.Wallet{w}, want: w.address(DefaultDerivationPath, 0)},
+		{
+			name:    "address",
+			wallets: []accounts.Wallet{w},
+			args:    []string{"-a", w.address(DefaultDerivationPath, 2).Hex()},
+			want:    w.address(DefaultDerivationPath, 2),
+		},
+		{
+			name:    "derivation path",
+			wallets: []accounts.Wallet{w},
+			args:    []string{"-d", "m/44'/60'/0'/x", "-a", w.address("m/44'/60'/0'/x", 1).Hex()},
+			want:    w.address("m/44'/60'/0'/x", 1),
+		},
+		{name: "no wallets", err: errWalletNotFound},
+		{name: "several wallets", wallets: []accounts.Wallet{w, trezor}, err: errors.New("more than one hardware wallet detected")},
+		{
+			name:    "address not found",
+			wallets: []accounts.Wallet{w},
+			args:    []string{"-a", w.address(DefaultDerivationPath, 5).Hex()},
+			err:     errSignerAddressNotfound,
+		},
+		{
+			name:    "pin and passphrase",
+			wallets: []accounts.Wallet{trezor},
+			secrets: []string{"1234", "secret"},
+			want:    trezor.address(DefaultDerivationPath, 0),
+		},
+		{name: "invalid pin", wallets: []accounts.Wallet{trezor}, secrets: []string{"4321"}, err: errors.New("can't open wallet: invalid pin")},
+		{name: "no pin", wallets: []accounts.Wallet{trezor}, err: errAborted},
+		{name: "no passphrase", wallets: []accounts.Wallet{trezor}, secrets: []string{"1234"}, err: errAborted},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w.open = false
+			trezor.open, trezor.unlocked = false, false
+			s, err := testHubBackend(t, tt.args, tt.wallets...).FromFlags(testEnv(tt.secrets...))
+			if tt.err != nil {
+				if err == nil || (!errors.Is(err, tt.err) && err.Error() != tt.err.Error()) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				for _, i := range tt.wallets {
+					if i.(*fakeWallet).open {
+						t.Errorf("wallet %s left open", i.URL())
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Address() != tt.want {
+				t.Errorf("got address %s, want %s", s.Address().Hex(), tt.want.Hex())
+			}
+			if err = Close(s); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestHubBackendFromPrompt(t *testing.T) {
+	w1, w2 := newFakeWallet("ledger", "1"), newFakeWallet("ledger", "2")
+	p := &fakePrompter{choices: []string{
+		w2.URL().String(),
+		DefaultDerivationPath,
+		"more",
+		w2.address(DefaultDerivationPath, 6).Hex(),
+	}}
+	s, err := testHubBackend(t, nil, w1, w2).FromPrompt(testEnv(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(s)
+	if want := w2.address(DefaultDerivationPath, 6); s.Address() != want {
+		t.Errorf("got address %s, want %s", s.Address().Hex(), want.Hex())
+	}
+	if w1.open {
+		t.Errorf("wallet %s opened", w1.URL())
+	}
+}
+
+func TestTrezorLegacyOnly(t *testing.T) {
+	for _, tt := range []struct {
+		scheme string
+		tx     types.TxData
+		err    error
+	}{
+		{"ledger", &types.DynamicFeeTx{}, nil},
+		{usbwallet.TrezorScheme, &types.LegacyTx{}, nil},
+		{usbwallet.TrezorScheme, &types.DynamicFeeTx{}, errLegacyOnly},
+		{usbwallet.TrezorScheme, &types.AccessListTx{}, errLegacyOnly},
+	} {
+		s := NewWallet(newFakeWallet(tt.scheme, "1"), accounts.Account{})
+		if got, want := LegacyOnly(s), tt.scheme == usbwallet.TrezorScheme; got != want {
+			t.Errorf("%s: got legacy only %t, want %t", tt.scheme, got, want)
+		}
+		if _, err := s.SignTx(types.NewTx(tt.tx), big.NewInt(1)); err != tt.err {
+			t.Errorf("%s, type %d: got error %v, want %v", tt.scheme, types.NewTx(tt.tx).Type(), err, tt.err)
+		}
+	}
+}
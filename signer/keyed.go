@@ -0,0 +1,144 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func init() { Register(func() Backend { return &keyBackend{} }) }
+
+// keyed signs with a private key
+type keyed struct {
+	key  *ecdsa.PrivateKey
+	desc string
+}
+
+// NewKeyed returns a signer with key, described as desc
+func NewKeyed(key *ecdsa.PrivateKey, desc string) Signer { return &keyed{key: key, desc: desc} }
+
+func (k *keyed) Address() common.Address { return crypto.PubkeyToAddress(k.key.PublicKey) }
+func (k *keyed) Describe() string        { return k.desc }
+
+func (k *keyed) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), k.key)
+}
+
+func (k *keyed) SignMessage(msg []byte) ([]byte, error) {
+	sig, err := crypto.Sign(accounts.TextHash(msg), k.key)
+	if err != nil {
+		return nil, err
+	}
+	return signature(sig), nil
+}
+
+func (k *keyed) SignTypedData(data *apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(*data)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(hash, k.key)
+	if err != nil {
+		return nil, err
+	}
+	return signature(sig), nil
+}
+
+// ParseKey parses a hex private key or, if encrypted, a keystore file
+func ParseKey(b []byte, encrypted bool, password string) (*ecdsa.PrivateKey, error) {
+	if encrypted {
+		ksk, err := keystore.DecryptKey(b, password)
+		if err != nil {
+			return nil, fmt.Errorf("can't decrypt key: %w", err)
+		}
+		return ksk.PrivateKey, nil
+	}
+	k, err := crypto.HexToECDSA(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("can't import key: %w", err)
+	}
+	return k, nil
+}
+
+// keyBackend reads a raw or an encrypted key file
+type keyBackend struct {
+	rawKey   string
+	encKey   string
+	password string
+}
+
+func (kb *keyBackend) Name() string        { return "key" }
+func (kb *keyBackend) Description() string { return "sign with a key" }
+
+func (kb *keyBackend) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&kb.rawKey, "k", kb.rawKey, "raw key")
+	fs.StringVar(&kb.encKey, "e", kb.encKey, "encrypted key")
+	fs.StringVar(&kb.password, "P", kb.password, "password for the encrypted key")
+}
+
+func (kb *keyBackend) Configured() bool {
+	return kb.rawKey != "" || kb.encKey != "" || kb.password != ""
+}
+
+func (kb *keyBackend) FromFlags(env *Env) (Signer, error) {
+	if kb.rawKey != "" && kb.encKey != "" {
+		return nil, errors.New("-e and -k are mutually exclusive")
+	}
+	if kb.rawKey != "" && kb.password != "" {
+		return nil, errors.New("-P is the password of -e")
+	}
+	fn, encrypted := kb.rawKey, false
+	if kb.encKey != "" {
+		fn, encrypted = kb.encKey, true
+		if kb.password == "" {
+			var err error
+			if kb.password, err = env.ReadPassword(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if fn == "" {
+		return nil, errors.New("-P is the password of -e")
+	}
+	return readKey(fn, encrypted, kb.password)
+}
+
+func (kb *keyBackend) FromPrompt(env *Env, p Prompter) (Signer, error) {
+	fn, err := p.Filename("key file: ")
+	if err != nil {
+		return nil, err
+	}
+	encrypted, ok := p.YesNo("encrypted?", false)
+	if !ok {
+		return nil, errors.New("bad response")
+	}
+	var password string
+	if encrypted {
+		if password, err = p.Password("password: "); err != nil {
+			return nil, err
+		}
+	}
+	return readKey(fn, encrypted, password)
+}
+
+func readKey(fn string, encrypted bool, password string) (Signer, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("can't read file: %w", err)
+	}
+	key, err := ParseKey(b, encrypted, password)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyed(key, "key file "+fn), nil
+}
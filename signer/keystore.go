@@ -0,0 +1,213 @@
+package signer
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func init() { Register(func() Backend { return &keystoreBackend{} }) }
+
+// keystoreAccount signs with an account of a keystore directory. The account
+// is unlocked for a session, unlock is called to unlock it again when it
+// expires
+type keystoreAccount struct {
+	ks      *keystore.KeyStore
+	account accounts.Account
+	unlock  func() error
+}
+
+func (ka *keystoreAccount) Address() common.Address { return ka.account.Address }
+func (ka *keystoreAccount) Describe() string        { return "keystore account " + ka.account.URL.Path }
+
+// relocked runs f and, if the session expired, unlocks the account and runs
+// it again
+func (ka *keystoreAccount) relocked(f func() error) error {
+	err := f()
+	if !errors.Is(err, keystore.ErrLocked) {
+		return err
+	}
+	if err = ka.unlock(); err != nil {
+		return err
+	}
+	return f()
+}
+
+func (ka *keystoreAccount) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	var r *types.Transaction
+	err := ka.relocked(func() (err error) {
+		r, err = ka.ks.SignTx(ka.account, tx, chainID)
+		return err
+	})
+	return r, err
+}
+
+func (ka *keystoreAccount) signHash(hash []byte) ([]byte, error) {
+	var sig []byte
+	err := ka.relocked(func() (err error) {
+		sig, err = ka.ks.SignHash(ka.account, hash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return signature(sig), nil
+}
+
+func (ka *keystoreAccount) SignMessage(msg []byte) ([]byte, error) {
+	return ka.signHash(accounts.TextHash(msg))
+}
+
+func (ka *keystoreAccount) SignTypedData(data *apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(*data)
+	if err != nil {
+		return nil, err
+	}
+	return ka.signHash(hash)
+}
+
+func (ka *keystoreAccount) Close() error { return ka.ks.Lock(ka.account.Address) }
+
+// openKeystore loads the keystore in dir, which must have accounts
+func openKeystore(dir string) (*keystore.KeyStore, error) {
+	if st, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("can't open keystore: %w", err)
+	} else if !st.IsDir() {
+		return nil, fmt.Errorf("can't open keystore: %s isn't a directory", dir)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP)
+	if len(ks.Accounts()) == 0 {
+		return nil, fmt.Errorf("no accounts in keystore %s", dir)
+	}
+	return ks, nil
+}
+
+// keystoreBackend signs with an account of a geth keystore directory
+type keystoreBackend struct {
+	dir      string
+	account  string
+	password string
+	unlock   time.Duration
+}
+
+func (kb *keystoreBackend) Name() string        { return "keystore" }
+func (kb *keystoreBackend) Description() string { return "sign with a keystore account" }
+
+func (kb *keystoreBackend) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&kb.dir, "keystore", kb.dir, "keystore directory")
+	fs.StringVar(&kb.account, "keystore-account", kb.account, "address, @name or index of the keystore account (empty to use the first one)")
+	fs.StringVar(&kb.password, "keystore-password", kb.password, "password of the keystore account")
+	fs.DurationVar(&kb.unlock, "keystore-unlock", kb.unlock, "time the keystore account stays unlocked (0 until exit)")
+}
+
+func (kb *keystoreBackend) Configured() bool {
+	return kb.dir != "" || kb.account != "" || kb.password != ""
+}
+
+func (kb *keystoreBackend) FromFlags(env *Env) (Signer, error) {
+	if kb.dir == "" {
+		return nil, errors.New("-keystore-account and -keystore-password need -keystore")
+	}
+	ks, err := openKeystore(kb.dir)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := findKeystoreAccount(ks, kb.account, env)
+	if err != nil {
+		return nil, err
+	}
+	return unlockAccount(ks, acc, kb.unlock, kb.password, func() (string, error) {
+		fmt.Fprintf(os.Stderr, "password for %s: ", acc.Address.Hex())
+		return env.ReadPassword()
+	})
+}
+
+// findKeystoreAccount finds the account by index or address, the first one if
+// s is empty
+func findKeystoreAccount(ks *keystore.KeyStore, s string, env *Env) (accounts.Account, error) {
+	accs := ks.Accounts()
+	if s == "" {
+		return accs[0], nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n >= len(accs) {
+			return accounts.Account{}, fmt.Errorf("invalid account index %d, the keystore has %d accounts", n, len(accs))
+		}
+		return accs[n], nil
+	}
+	addr, err := env.ResolveAddress(s)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	acc, err := ks.Find(accounts.Account{Address: addr})
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("%s: %w", addr.Hex(), err)
+	}
+	return acc, nil
+}
+
+// unlockAccount unlocks acc for d with password, or with the one read by
+// readPassword if it's empty. readPassword is also used when the session
+// expires
+func unlockAccount(ks *keystore.KeyStore, acc accounts.Account, d time.Duration, password string, readPassword func() (string, error)) (Signer, error) {
+	unlock := func() error {
+		p, err := readPassword()
+		if err != nil {
+			return err
+		}
+		return ks.TimedUnlock(acc, p, d)
+	}
+	if password == "" {
+		if err := unlock(); err != nil {
+			return nil, fmt.Errorf("can't unlock account: %w", err)
+		}
+	} else if err := ks.TimedUnlock(acc, password, d); err != nil {
+		return nil, fmt.Errorf("can't unlock account: %w", err)
+	}
+	return &keystoreAccount{ks: ks, account: acc, unlock: unlock}, nil
+}
+
+func (kb *keystoreBackend) FromPrompt(env *Env, p Prompter) (Signer, error) {
+	dir, err := p.Directory("keystore directory: ")
+	if err != nil {
+		return nil, err
+	}
+	ks, err := openKeystore(dir)
+	if err != nil {
+		return nil, err
+	}
+	accs := ks.Accounts()
+	addrs := make([]string, 0, len(accs))
+	for _, i := range accs {
+		addrs = append(addrs, i.Address.Hex())
+	}
+	a, ok := p.Choice("account", addrs[0], addrs)
+	if !ok {
+		return nil, errAborted
+	}
+	acc := accs[0]
+	for _, i := range accs {
+		if i.Address.Hex() == a {
+			acc = i
+		}
+	}
+	var d time.Duration
+	if s := p.Text("unlock for (e.g. 10m, empty until exit): "); s != "" {
+		if d, err = time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("invalid duration: %w", err)
+		}
+	}
+	return unlockAccount(ks, acc, d, "", func() (string, error) {
+		return p.Password(fmt.Sprintf("password for %s: ", acc.Address.Hex()))
+	})
+}
@@ -0,0 +1,186 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+func init() { Register(func() Backend { return newMnemonicBackend() }) }
+
+// MnemonicDerivationPath is the derivation path of the accounts of mnemonics,
+// as used by hardhat, anvil and metamask
+const MnemonicDerivationPath = "m/44'/60'/0'/0/x"
+
+var errInvalidKey = errors.New("invalid derived key")
+
+// deriveKey derives the private key of path from a bip-39 seed, as in bip-32
+func deriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	n := crypto.S256().Params().N
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	i := mac.Sum(nil)
+	key, chainCode := new(big.Int).SetBytes(i[:32]), i[32:]
+	if key.Sign() == 0 || key.Cmp(n) >= 0 {
+		return nil, errInvalidKey
+	}
+	for _, c := range path {
+		mac := hmac.New(sha512.New, chainCode)
+		if c >= 0x80000000 {
+			// hardened child, from the private key
+			mac.Write([]byte{0})
+			mac.Write(math.PaddedBigBytes(key, 32))
+		} else {
+			k, err := crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+			if err != nil {
+				return nil, err
+			}
+			mac.Write(crypto.CompressPubkey(&k.PublicKey))
+		}
+		var cb [4]byte
+		binary.BigEndian.PutUint32(cb[:], c)
+		mac.Write(cb[:])
+		i := mac.Sum(nil)
+		il := new(big.Int).SetBytes(i[:32])
+		if il.Cmp(n) >= 0 {
+			return nil, errInvalidKey
+		}
+		if key.Add(key, il).Mod(key, n).Sign() == 0 {
+			return nil, errInvalidKey
+		}
+		chainCode = i[32:]
+	}
+	return crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+}
+
+// mnemonicSeed returns the seed of a bip-39 mnemonic and its passphrase
+func mnemonicSeed(mnemonic, passphrase string) ([]byte, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, errors.New("invalid mnemonic")
+	}
+	return bip39.NewSeed(mnemonic, passphrase), nil
+}
+
+// seedDerive derives the addresses of a seed
+func seedDerive(seed []byte) deriveFunc {
+	return func(dp string, n int) (common.Address, error) {
+		key, err := seedKey(seed, dp, n)
+		if err != nil {
+			return common.Address{}, err
+		}
+		return crypto.PubkeyToAddress(key.PublicKey), nil
+	}
+}
+
+// seedKey derives the key n of the derivation path template dp
+func seedKey(seed []byte, dp string, n int) (*ecdsa.PrivateKey, error) {
+	p, err := derivationPath(dp, n)
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(seed, p)
+	if err != nil {
+		return nil, fmt.Errorf("can't derive key: %w", err)
+	}
+	return key, nil
+}
+
+// newMnemonicSigner returns the signer of the key n of dp
+func newMnemonicSigner(seed []byte, dp string, n int) (Signer, error) {
+	key, err := seedKey(seed, dp, n)
+	if err != nil {
+		return nil, err
+	}
+	p, _ := derivationPath(dp, n)
+	return NewKeyed(key, "mnemonic account "+p.String()), nil
+}
+
+// mnemonicBackend derives the key from a bip-39 mnemonic
+type mnemonicBackend struct {
+	file           string
+	passphrase     string
+	derivationPath string
+	account        string
+}
+
+func newMnemonicBackend() *mnemonicBackend {
+	return &mnemonicBackend{derivationPath: MnemonicDerivationPath}
+}
+
+func (mb *mnemonicBackend) Name() string        { return "mnemonic" }
+func (mb *mnemonicBackend) Description() string { return "sign with a key of a mnemonic" }
+
+func (mb *mnemonicBackend) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&mb.file, "mnemonic", mb.file, "file with the mnemonic")
+	fs.StringVar(&mb.passphrase, "mnemonic-passphrase", mb.passphrase, "passphrase of the mnemonic")
+	fs.StringVar(&mb.derivationPath, "mnemonic-path", mb.derivationPath, "derivation path of the mnemonic accounts")
+	fs.StringVar(&mb.account, "mnemonic-account", mb.account, "address, @name or index of the mnemonic account (empty to use the first one)")
+}
+
+func (mb *mnemonicBackend) Configured() bool {
+	return mb.file != "" || mb.passphrase != "" || mb.account != ""
+}
+
+func (mb *mnemonicBackend) FromFlags(env *Env) (Signer, error) {
+	if mb.file == "" {
+		return nil, errors.New("-mnemonic-passphrase and -mnemonic-account need -mnemonic")
+	}
+	b, err := ioutil.ReadFile(mb.file)
+	if err != nil {
+		return nil, fmt.Errorf("can't read file: %w", err)
+	}
+	seed, err := mnemonicSeed(string(b), mb.passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	if mb.account != "" {
+		if n, err = strconv.Atoi(mb.account); err != nil {
+			addr, err := env.ResolveAddress(mb.account)
+			if err != nil {
+				return nil, err
+			}
+			if n, err = findIndex(seedDerive(seed), mb.derivationPath, &addr); err != nil {
+				return nil, err
+			}
+		} else if n < 0 {
+			return nil, fmt.Errorf("invalid account index %d", n)
+		}
+	}
+	return newMnemonicSigner(seed, mb.derivationPath, n)
+}
+
+func (mb *mnemonicBackend) FromPrompt(env *Env, p Prompter) (Signer, error) {
+	mnemonic, err := p.Password("mnemonic: ")
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := p.Password("passphrase (empty for none): ")
+	if err != nil {
+		return nil, err
+	}
+	seed, err := mnemonicSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	dp, n, err := promptIndex(seedDerive(seed), p, MnemonicDerivationPath)
+	if err != nil {
+		return nil, err
+	}
+	return newMnemonicSigner(seed, dp, n)
+}
@@ -0,0 +1,72 @@
+package signer
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testMnemonic = "test test test test test test test test test test test junk"
+
+func TestDeriveKeyMnemonic(t *testing.T) {
+	seed, err := mnemonicSeed(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the accounts of hardhat and anvil
+	for i, want := range []string{
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		"0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		"0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	} {
+		addr, err := seedDerive(seed)(MnemonicDerivationPath, i)
+		if err != nil {
+			t.Fatalf("account %d: %s", i, err)
+		}
+		if addr != common.HexToAddress(want) {
+			t.Errorf("account %d: got %s, want %s", i, addr.Hex(), want)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	hhSeed, err := mnemonicSeed(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1Seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	for _, tt := range []struct {
+		name string
+		seed []byte
+		path string
+		key  string
+	}{
+		// bip-32 test vector 1
+		{"bip32 master", v1Seed, "m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{"bip32 hardened", v1Seed, "m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"bip32 normal", v1Seed, "m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"bip32 chain", v1Seed, "m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+		// keys with a leading zero byte, that must be left-padded
+		{"short key", hhSeed, "m/44'/60'/0'/0/140", "00d0d1d0ad58ae173096d9c2ce22a3afaa6b7bfd2303e02b5be06ba14166287d"},
+		{"short parent", hhSeed, "m/44'/60'/21'/0'", "9c33e428984bbfa0ac9d73e439438a37951cea0db2372c4c4b1baa97db56a348"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p accounts.DerivationPath
+			if tt.path != "m" {
+				if p, err = accounts.ParseDerivationPath(tt.path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			key, err := deriveKey(tt.seed, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(crypto.FromECDSA(key)); got != tt.key {
+				t.Errorf("got %s, want %s", got, tt.key)
+			}
+		})
+	}
+}
@@ -1,75 +1,66 @@
 package signer
 
 import (
-	"crypto/ecdsa"
 	"errors"
+	"io"
 	"math/big"
 
-	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
-type Signer struct {
-	Key    *ecdsa.PrivateKey
-	Wallet *walletSigner
+// Signer signs transactions and messages with the key of an address
+type Signer interface {
+	Address() common.Address
+	// SignTx signs tx for the chain with chainID
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// SignMessage signs msg as an EIP-191 personal message
+	SignMessage(msg []byte) ([]byte, error)
+	// SignTypedData signs EIP-712 typed data
+	SignTypedData(data *apitypes.TypedData) ([]byte, error)
+	// Describe returns what the signer is, to show it to the user
+	Describe() string
 }
 
-type walletSigner struct {
-	Wallet  accounts.Wallet
-	Account *accounts.Account
-}
-
-func NewKeyed(key *ecdsa.PrivateKey) *Signer { return &Signer{Key: key} }
+var (
+	ErrNoSigner        = errors.New("signer not configured")
+	ErrAddressNotFound = errors.New("address not found")
+)
 
-func NewLedger(w accounts.Wallet, ac *accounts.Account) *Signer {
-	return &Signer{
-		Wallet: &walletSigner{
-			Wallet:  w,
-			Account: ac,
+// TransactOpts returns the options to sign transactions with s in the chain
+// with chainID
+func TransactOpts(s Signer, chainID *big.Int) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: s.Address(),
+		Signer: func(fromAddr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if s.Address() != fromAddr {
+				return nil, ErrAddressNotFound
+			}
+			return s.SignTx(tx, chainID)
 		},
 	}
 }
 
-type Kind int
-
-const (
-	None Kind = iota
-	Keyed
-	HardwareWallet
-)
-
-func (s *Signer) Kind() Kind {
-	if s.Key != nil {
-		return Keyed
-	}
-	if s.Wallet != nil {
-		return HardwareWallet
+// Close releases what s holds, like an open hardware wallet
+func Close(s Signer) error {
+	if c, ok := s.(io.Closer); ok {
+		return c.Close()
 	}
-	return None
+	return nil
 }
 
-var (
-	ErrNoSigner        = errors.New("signer not configured")
-	ErrAddressNotFound = errors.New("address not found")
-)
+// LegacyOnly reports whether s can only sign legacy transactions
+func LegacyOnly(s Signer) bool {
+	l, ok := s.(interface{ LegacyOnly() bool })
+	return ok && l.LegacyOnly()
+}
 
-func (s *Signer) TransactOpts(chainID *big.Int) (*bind.TransactOpts, error) {
-	switch sk := s.Kind(); sk {
-	case None:
-		return nil, ErrNoSigner
-	case Keyed:
-		return bind.NewKeyedTransactor(s.Key), nil
-	case HardwareWallet:
+// signature returns sig with the recovery id as 27 or 28, like eth_sign
+func signature(sig []byte) []byte {
+	if len(sig) == 65 && sig[64] < 27 {
+		sig[64] += 27
 	}
-	return &bind.TransactOpts{
-		From: s.Wallet.Account.Address,
-		Signer: func(signer types.Signer, fromAddr common.Address, tx *types.Transaction) (*types.Transaction, error) {
-			if s.Wallet.Account.Address != fromAddr {
-				return nil, ErrAddressNotFound
-			}
-			return s.Wallet.Wallet.SignTx(*s.Wallet.Account, tx, chainID)
-		},
-	}, nil
+	return sig
 }
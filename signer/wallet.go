@@ -0,0 +1,91 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// wallet signs with an account of a hardware wallet
+type wallet struct {
+	w       accounts.Wallet
+	account accounts.Account
+}
+
+// NewWallet returns a signer with the account of the open wallet w
+func NewWallet(w accounts.Wallet, account accounts.Account) Signer {
+	return &wallet{w: w, account: account}
+}
+
+func (w *wallet) Address() common.Address { return w.account.Address }
+
+func (w *wallet) Describe() string {
+	r := "hardware wallet " + w.w.URL().String()
+	if st, err := w.w.Status(); err == nil {
+		r += " (" + st + ")"
+	}
+	return r
+}
+
+var errLegacyOnly = errors.New("trezor can only sign legacy transactions, build them with -legacy")
+
+// LegacyOnly reports whether the wallet is a trezor, the driver of geth
+// doesn't sign typed transactions
+func (w *wallet) LegacyOnly() bool { return w.w.URL().Scheme == usbwallet.TrezorScheme }
+
+func (w *wallet) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if tx.Type() != types.LegacyTxType && w.LegacyOnly() {
+		return nil, errLegacyOnly
+	}
+	return w.w.SignTx(w.account, tx, chainID)
+}
+
+func (w *wallet) SignMessage(msg []byte) ([]byte, error) {
+	sig, err := w.w.SignText(w.account, msg)
+	if err != nil {
+		return nil, err
+	}
+	return signature(sig), nil
+}
+
+func (w *wallet) SignTypedData(data *apitypes.TypedData) ([]byte, error) {
+	// the wallets sign the domain separator and the message hash
+	_, raw, err := apitypes.TypedDataAndHash(*data)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := w.w.SignData(w.account, accounts.MimetypeTypedData, []byte(raw))
+	if err != nil {
+		return nil, err
+	}
+	return signature(sig), nil
+}
+
+func (w *wallet) Close() error { return w.w.Close() }
+
+// deriveAccount derives the account n of the derivation path template dp
+func deriveAccount(w accounts.Wallet, dp string, n int) (accounts.Account, error) {
+	adp, err := derivationPath(dp, n)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	acc, err := w.Derive(adp, true)
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("can't derive address: %w", err)
+	}
+	return acc, nil
+}
+
+// walletDerive derives the addresses of w
+func walletDerive(w accounts.Wallet) deriveFunc {
+	return func(dp string, n int) (common.Address, error) {
+		acc, err := deriveAccount(w, dp, n)
+		return acc.Address, err
+	}
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/c-bata/go-prompt"
+)
+
+// entries kept for each key
+const maxHistoryEntries = 200
+
+// history of the prompts, nil when it isn't persisted
+var history *inputHistory
+
+type inputHistory struct {
+	path    string
+	entries map[string][]string
+}
+
+// LoadHistory reads the prompt history from path and saves the new entries
+// there. The file is created when the first entry is added
+func LoadHistory(path string) error {
+	h := &inputHistory{path: path, entries: make(map[string][]string, 16)}
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err = json.Unmarshal(b, &h.entries); err != nil {
+			return err
+		}
+	}
+	history = h
+	return nil
+}
+
+// HistoryOption returns the option that recalls the entries of key with the
+// up and down arrows
+func HistoryOption(key string) prompt.Option {
+	if history == nil {
+		return prompt.OptionHistory(nil)
+	}
+	return prompt.OptionHistory(history.entries[key])
+}
+
+// AddHistory appends entry to the history of key
+func AddHistory(key string, entry string) {
+	if history == nil || entry == "" || entry == UpCommand.Suggestion.Text {
+		return
+	}
+	e := history.entries[key]
+	if len(e) > 0 && e[len(e)-1] == entry {
+		return
+	}
+	e = append(e, entry)
+	if len(e) > maxHistoryEntries {
+		e = e[len(e)-maxHistoryEntries:]
+	}
+	history.entries[key] = e
+	if err := history.save(); err != nil {
+		fmt.Printf("can't save history: %s\n", err)
+	}
+}
+
+func (h *inputHistory) save() error {
+	b, err := json.Marshal(h.entries)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(h.path, b, 0600)
+}
@@ -294,27 +294,68 @@ func InputText(pr string) string {
 	)
 }
 
+// separators of the words completed in the text prompts
+const wordSeparators = " ,[]{}\":"
+
+// InputTextHistory reads a line, recalling and recording the entries of the
+// history of key. completer can be nil
+func InputTextHistory(pr string, key string, completer prompt.Completer) string {
+	if completer == nil {
+		completer = func(prompt.Document) []prompt.Suggest { return nil }
+	}
+	r := prompt.Input(
+		pr,
+		completer,
+		HistoryOption(key),
+		prompt.OptionCompletionWordSeparator(wordSeparators),
+	)
+	AddHistory(key, r)
+	return r
+}
+
+// AddressCompleter suggests the names in the address book for the words
+// starting with @
+func AddressCompleter(doc prompt.Document) []prompt.Suggest {
+	w := doc.GetWordBeforeCursorUntilSeparator(wordSeparators)
+	if !strings.HasPrefix(w, "@") {
+		return nil
+	}
+	names := internal.Addresses.Names()
+	r := make([]prompt.Suggest, 0, len(names))
+	for _, i := range names {
+		a, _ := internal.Addresses.Lookup(i)
+		r = append(r, prompt.Suggest{Text: "@" + i, Description: a.Hex()})
+	}
+	return prompt.FilterHasPrefix(r, w, false)
+}
+
 func InputBigInt(pr string) *big.Int {
 	for {
 		v := InputText(pr)
 		if v == "" {
 			continue
 		}
-		if r, ok := new(big.Int).SetString(v, 10); ok {
-			return r
+		r, err := internal.ParseAmount(v)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			continue
 		}
+		return r
 	}
 }
 
 func InputBigIntWithDefault(pr string, d *big.Int) *big.Int {
 	for {
-		v := InputText(fmt.Sprintf(pr, d))
+		v := InputText(fmt.Sprintf(pr, internal.DisplayAmount(d)))
 		if v == "" {
 			return d
 		}
-		if r, ok := new(big.Int).SetString(v, 10); ok {
-			return r
+		r, err := internal.ParseAmount(v)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			continue
 		}
+		return r
 	}
 }
 